@@ -0,0 +1,128 @@
+// Package cache locates the project root and the cache directory shared by all cardboard modules.
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// Environment variable overriding the cache directory.
+	// Relative paths are resolved against the project root.
+	DirEnv = "CARDBOARD_CACHE_DIR"
+	// Default cache directory, relative to the project root.
+	DefaultDir = ".cache"
+)
+
+// Files marking the project root, in order of precedence.
+var rootMarkers = []string{"go.work", "go.mod", ".git"}
+
+// ProjectRoot returns the root directory of the project containing the current working directory.
+// The root is the closest parent directory containing a go.work file,
+// otherwise a go.mod file, otherwise a .git directory.
+// Falls back to the current working directory if none of them are found.
+func ProjectRoot() (string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("getting working directory: %w", err)
+	}
+	return projectRootFrom(wd)
+}
+
+func projectRootFrom(dir string) (string, error) {
+	for _, marker := range rootMarkers {
+		root, err := findUpwards(dir, marker)
+		if err != nil {
+			return "", err
+		}
+		if len(root) > 0 {
+			return root, nil
+		}
+	}
+	return dir, nil
+}
+
+// Returns the closest parent of dir containing name or an empty string.
+func findUpwards(dir, name string) (string, error) {
+	for {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if err == nil {
+			return dir, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("looking up %s: %w", name, err)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// Dir returns the absolute path of the cache directory.
+// Defaults to .cache in the project root, can be overridden via CARDBOARD_CACHE_DIR.
+func Dir() (string, error) {
+	dir := os.Getenv(DirEnv)
+	if len(dir) == 0 {
+		dir = DefaultDir
+	}
+	return Resolve(dir)
+}
+
+// Resolve returns the absolute path of the given cache directory,
+// relative paths are resolved against the project root.
+func Resolve(dir string) (string, error) {
+	if filepath.IsAbs(dir) {
+		return filepath.Clean(dir), nil
+	}
+	root, err := ProjectRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, dir), nil
+}
+
+// Path returns the absolute path of elem within the cache directory.
+func Path(elem ...string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(append([]string{dir}, elem...)...), nil
+}
+
+// MustPath is like Path, but panics on error.
+func MustPath(elem ...string) string {
+	p, err := Path(elem...)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Clean removes the given sub directories from the cache directory
+// or the whole cache directory if none are given.
+func Clean(subDirs ...string) error {
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	if len(subDirs) == 0 {
+		return os.RemoveAll(dir)
+	}
+
+	for _, subDir := range subDirs {
+		p := filepath.Join(dir, subDir)
+		if rel, err := filepath.Rel(dir, p); err != nil || rel == "." || !filepath.IsLocal(rel) {
+			return fmt.Errorf("%q is not a sub directory of the cache directory", subDir)
+		}
+		if err := os.RemoveAll(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_projectRootFrom(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	mod := filepath.Join(root, "mod")
+	sub := filepath.Join(mod, "sub", "dir")
+	require.NoError(t, os.MkdirAll(sub, 0o755))
+	require.NoError(t, os.Mkdir(filepath.Join(root, ".git"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(mod, "go.mod"), nil, 0o600))
+
+	r, err := projectRootFrom(sub)
+	require.NoError(t, err)
+	assert.Equal(t, mod, r, "go.mod takes precedence over .git")
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "go.work"), nil, 0o600))
+	r, err = projectRootFrom(sub)
+	require.NoError(t, err)
+	assert.Equal(t, root, r, "go.work takes precedence over go.mod")
+}
+
+func TestDir(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "sub")
+	require.NoError(t, os.Mkdir(sub, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "go.mod"), nil, 0o600))
+	t.Chdir(sub)
+
+	t.Setenv(DirEnv, "")
+	dir, err := Dir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, DefaultDir), dir)
+
+	t.Setenv(DirEnv, "custom")
+	dir, err = Dir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "custom"), dir)
+
+	t.Setenv(DirEnv, "/tmp/abs")
+	p, err := Path("clusters", "banana")
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/abs/clusters/banana", p)
+}
+
+func TestClean(t *testing.T) {
+	cacheDir := t.TempDir()
+	t.Setenv(DirEnv, cacheDir)
+	for _, d := range []string{"unit", "oci"} {
+		require.NoError(t, os.Mkdir(filepath.Join(cacheDir, d), 0o755))
+	}
+
+	require.NoError(t, Clean("unit"))
+	assert.NoDirExists(t, filepath.Join(cacheDir, "unit"))
+	assert.DirExists(t, filepath.Join(cacheDir, "oci"))
+
+	require.EqualError(t, Clean("../banana"), `"../banana" is not a sub directory of the cache directory`)
+	require.EqualError(t, Clean("."), `"." is not a sub directory of the cache directory`)
+
+	require.NoError(t, Clean())
+	assert.NoDirExists(t, cacheDir)
+}
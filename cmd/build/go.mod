@@ -0,0 +1,21 @@
+module pkg.package-operator.run/cardboard/cmd/build
+
+go 1.26.0
+
+require (
+	pkg.package-operator.run/cardboard v0.0.4
+	pkg.package-operator.run/cardboard/modules/gotest v0.0.4
+)
+
+require (
+	github.com/mattn/go-isatty v0.0.23 // indirect
+	github.com/xlab/treeprint v1.2.0 // indirect
+	golang.org/x/mod v0.41.0 // indirect
+	golang.org/x/sys v0.47.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace (
+	pkg.package-operator.run/cardboard => ../../
+	pkg.package-operator.run/cardboard/modules/gotest => ../../modules/gotest
+)
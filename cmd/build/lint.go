@@ -5,6 +5,7 @@ import (
 
 	"pkg.package-operator.run/cardboard/run"
 	"pkg.package-operator.run/cardboard/sh"
+	"pkg.package-operator.run/cardboard/workspace"
 )
 
 // internal struct to namespace all lint related functions.
@@ -13,31 +14,41 @@ type Lint struct{}
 func (l Lint) Fix(ctx context.Context) error   { return l.glciFix(ctx) }
 func (l Lint) Check(ctx context.Context) error { return l.glciCheck(ctx) }
 
-// TODO make that more nice.
 func (l Lint) goModTidy(ctx context.Context, workdir string) error {
 	return shr.New(sh.WithWorkDir(workdir)).Run(ctx, "go", "mod", "tidy")
 }
 
 func (l Lint) goModTidyAll(ctx context.Context) error {
-	return mgr.ParallelDeps(ctx, run.Meth(l, l.goModTidyAll),
-		run.Meth1(l, l.goModTidy, "."),
-		run.Meth1(l, l.goModTidy, "./kubeutils/"),
-		run.Meth1(l, l.goModTidy, "./modules/kind/"),
-		run.Meth1(l, l.goModTidy, "./modules/kubeclients/"),
-		run.Meth1(l, l.goModTidy, "./modules/oci/"),
-	)
+	ws, err := workspace.Current()
+	if err != nil {
+		return err
+	}
+	deps := make([]run.Dependency, len(ws.Modules))
+	for i, m := range ws.Modules {
+		deps[i] = run.Meth1(l, l.goModTidy, m.Dir)
+	}
+	return mgr.ParallelDeps(ctx, run.Meth(l, l.goModTidyAll), deps...)
 }
 
-func (Lint) glciFix(ctx context.Context) error {
-	return shr.Run(ctx, "golangci-lint", "run", "--timeout", "2m", "--fix",
-		"./...", "./kubeutils/...", "./modules/kind/...", "./modules/kubeclients/...", "./modules/oci/...",
-	)
+func (l Lint) glciFix(ctx context.Context) error {
+	return l.glci(ctx, "--fix")
 }
 
-func (Lint) glciCheck(ctx context.Context) error {
-	return shr.Run(ctx, "golangci-lint", "run", "--timeout", "2m",
-		"./...", "./kubeutils/...", "./modules/kind/...", "./modules/kubeclients/...", "./modules/oci/...",
-	)
+func (l Lint) glciCheck(ctx context.Context) error {
+	return l.glci(ctx)
+}
+
+// Runs golangci-lint on the packages of all workspace modules.
+func (Lint) glci(ctx context.Context, args ...string) error {
+	ws, err := workspace.Current()
+	if err != nil {
+		return err
+	}
+	args = append([]string{"run", "--timeout", "2m"}, args...)
+	for _, m := range ws.Modules {
+		args = append(args, m.Packages())
+	}
+	return shr.Run(ctx, "golangci-lint", args...)
 }
 
 func (Lint) goWorkSync(ctx context.Context) error {
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"pkg.package-operator.run/cardboard/run"
 	"pkg.package-operator.run/cardboard/sh"
@@ -34,7 +35,6 @@ func main() {
 	lint = &Lint{}
 
 	err := errors.Join(
-		mgr.RegisterGoTool(ctx, "gotestfmt", "github.com/gotesttools/gotestfmt/v2/cmd/gotestfmt", "2.5.0"),
 		mgr.RegisterGoTool(ctx, "golangci-lint", "github.com/golangci/golangci-lint/v2/cmd/golangci-lint", "2.12.2"),
 		mgr.Register(&Dev{}, &CI{}),
 	)
@@ -66,18 +66,14 @@ func (ci *CI) Lint(ctx context.Context, _ []string) error {
 	return lint.Check(ctx)
 }
 
+// Ensures that code-gens and formatters leave the codebase unchanged.
 func (ci *CI) PostPush(ctx context.Context, args []string) error {
 	self := run.Meth1(ci, ci.PostPush, args)
-	err := mgr.SerialDeps(ctx, self,
+	return mgr.CheckUnchanged(ctx, self,
 		run.Meth(lint, lint.glciFix),
 		run.Meth(lint, lint.goWorkSync),
 		run.Meth(lint, lint.goModTidyAll),
 	)
-	if err != nil {
-		return err
-	}
-
-	return shr.Run(ctx, "git", "diff", "--exit-code")
 }
 
 // Development focused commands using local development environment.
@@ -109,15 +105,18 @@ func (d *Dev) LintFix(ctx context.Context, _ []string) error {
 }
 
 // common unittest target shared by CI and Dev.
+// Accepts a -run filter and --changed-since=<git ref> to only test affected packages.
 func commonUnit(ctx context.Context, args []string) error {
-	var filter string
-	switch len(args) {
-	case 0:
-		// nothing
-	case 1:
-		filter = args[0]
-	default:
-		return errors.New("only supports a single argument") //nolint:goerr113
+	var filter, changedSince string
+	for _, arg := range args {
+		if ref, ok := strings.CutPrefix(arg, "--changed-since="); ok {
+			changedSince = ref
+			continue
+		}
+		if len(filter) > 0 {
+			return errors.New("only supports a single filter argument") //nolint:goerr113
+		}
+		filter = arg
 	}
-	return test.Unit(ctx, filter)
+	return test.Unit(ctx, filter, changedSince)
 }
@@ -2,34 +2,22 @@ package main
 
 import (
 	"context"
-	"fmt"
-	"os"
-	"strings"
 
-	"pkg.package-operator.run/cardboard/sh"
+	"pkg.package-operator.run/cardboard/modules/gotest"
 )
 
 // internal struct to namespace all test related functions.
 type Test struct{}
 
 // Run unittests, the filter argument is passed via -run="".
-func (t Test) Unit(ctx context.Context, filter string) error {
-	if err := os.MkdirAll(".cache/unit", 0o755); err != nil {
-		return err
-	}
-
-	gotestArgs := []string{"-coverprofile=.cache/unit/cover.txt", "-race", "-json"}
+// If changedSince is set, only packages affected by changes since this git ref are tested.
+func (t Test) Unit(ctx context.Context, filter, changedSince string) error {
+	var args gotest.WithArgs
 	if len(filter) > 0 {
-		gotestArgs = append(gotestArgs, "-run="+filter)
+		args = append(args, "-run="+filter)
 	}
-
-	argStr := strings.Join(gotestArgs, " ")
-
-	return sh.New(
-		sh.WithEnvironment{"CGO_ENABLED": "1"},
-	).Bash(
-		ctx,
-		"set -euo pipefail",
-		fmt.Sprintf("go test %s ./... 2>&1 | tee .cache/unit/gotest.log | gotestfmt --hide=all", argStr),
-	)
+	return gotest.NewGoTest(mgr,
+		gotest.WithRace{}, gotest.WithCoverage{}, gotest.WithJUnit{}, args,
+		gotest.WithChangedSince(changedSince),
+	).Test(ctx)
 }
@@ -4,6 +4,8 @@ go 1.26.0
 
 replace (
 	pkg.package-operator.run/cardboard/kubeutils => ./kubeutils
+	pkg.package-operator.run/cardboard/modules/gobuild => ./modules/gobuild
+	pkg.package-operator.run/cardboard/modules/gotest => ./modules/gotest
 	pkg.package-operator.run/cardboard/modules/kind => ./modules/kind
 	pkg.package-operator.run/cardboard/modules/kubeclients => ./modules/kubeclients
 	pkg.package-operator.run/cardboard/modules/oci => ./modules/oci
@@ -14,6 +16,8 @@ require (
 	github.com/neilotoole/slogt v1.1.0
 	github.com/stretchr/testify v1.11.1
 	github.com/xlab/treeprint v1.2.0
+	golang.org/x/mod v0.41.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -23,5 +27,4 @@ require (
 	github.com/rogpeppe/go-internal v1.15.0 // indirect
 	golang.org/x/sys v0.47.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,21 @@
+module pkg.package-operator.run/cardboard/modules/gobuild
+
+go 1.26.0
+
+require (
+	github.com/stretchr/testify v1.11.1
+	pkg.package-operator.run/cardboard v0.0.4
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.2-0.20180830191138-d8f796af33cc // indirect
+	github.com/kr/text v0.2.0 // indirect
+	github.com/mattn/go-isatty v0.0.23 // indirect
+	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
+	github.com/xlab/treeprint v1.2.0 // indirect
+	golang.org/x/mod v0.41.0 // indirect
+	golang.org/x/sys v0.47.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace pkg.package-operator.run/cardboard => ../../
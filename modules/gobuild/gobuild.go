@@ -0,0 +1,268 @@
+// Package gobuild cross-compiles Go binaries for a matrix of platforms.
+package gobuild
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"pkg.package-operator.run/cardboard/cache"
+	"pkg.package-operator.run/cardboard/run"
+	"pkg.package-operator.run/cardboard/sh"
+)
+
+// Target platform of a build.
+type Platform struct {
+	OS, Arch string
+}
+
+func (p Platform) String() string { return p.OS + "/" + p.Arch }
+
+// Returns the platform the build is running on.
+func HostPlatform() Platform {
+	return Platform{OS: runtime.GOOS, Arch: runtime.GOARCH}
+}
+
+// Builds a Go binary for a matrix of platforms.
+type GoBuild struct {
+	mgr              *run.Manager
+	pkg, name        string
+	workDir          string
+	outputDir        string
+	platforms        []Platform
+	versionVariables []string
+	ldflags          []string
+	tags             []string
+	trimpath         bool
+	cgo              *bool
+}
+
+// Creates a new GoBuild for the given package, e.g. "./cmd/tool".
+func NewGoBuild(mgr *run.Manager, pkg string, opts ...Option) *GoBuild {
+	b := &GoBuild{
+		mgr:       mgr,
+		pkg:       pkg,
+		name:      path.Base(pkg),
+		platforms: []Platform{HostPlatform()},
+	}
+	for _, opt := range opts {
+		opt.ApplyToGoBuild(b)
+	}
+	return b
+}
+
+// ID includes all options, so builds of the same package with different settings are separate dependencies.
+func (b *GoBuild) ID() string {
+	cgo := "default"
+	if b.cgo != nil {
+		cgo = strconv.FormatBool(*b.cgo)
+	}
+	return fmt.Sprintf("pkg.package-operator.run/cardboard/modules/gobuild.GoBuild{pkg:%s,name:%s,workDir:%s,"+
+		"outputDir:%s,platforms:%v,versionVariables:%v,ldflags:%v,tags:%v,trimpath:%t,cgo:%s}",
+		b.pkg, b.name, b.workDir, b.outputDir, b.platforms, b.versionVariables, b.ldflags, b.tags, b.trimpath, cgo)
+}
+
+// Returns a Build dependency.
+func (b *GoBuild) Run(ctx context.Context) error {
+	return b.Build(ctx)
+}
+
+// Builds the binary for all platforms in parallel.
+func (b *GoBuild) Build(ctx context.Context) error {
+	var version string
+	if len(b.versionVariables) > 0 {
+		var err error
+		if version, err = b.gitDescribe(ctx); err != nil {
+			return err
+		}
+	}
+
+	deps := make([]run.Dependency, len(b.platforms))
+	for i, p := range b.platforms {
+		deps[i] = run.Meth2(b, b.buildPlatform, p, version)
+	}
+	return b.mgr.ParallelDeps(ctx, b, deps...)
+}
+
+// Returns the path of the binary built for the given platform.
+func (b *GoBuild) Artifact(p Platform) (string, error) {
+	outputDir := b.outputDir
+	if len(outputDir) == 0 {
+		var err error
+		if outputDir, err = cache.Path("gobuild", b.name); err != nil {
+			return "", err
+		}
+	}
+	name := fmt.Sprintf("%s_%s_%s", b.name, p.OS, p.Arch)
+	if p.OS == "windows" {
+		name += ".exe"
+	}
+	return filepath.Abs(filepath.Join(outputDir, name))
+}
+
+func (b *GoBuild) gitDescribe(ctx context.Context) (string, error) {
+	version, err := sh.New(sh.WithWorkDir(b.workDir)).
+		Output(ctx, "git", "describe", "--tags", "--always", "--dirty")
+	if err != nil {
+		return "", fmt.Errorf("describing version: %w", err)
+	}
+	return version, nil
+}
+
+func (b *GoBuild) buildPlatform(ctx context.Context, p Platform, version string) error {
+	artifact, err := b.Artifact(p)
+	if err != nil {
+		return err
+	}
+	args, env := b.buildArgs(p, version, artifact)
+
+	// Up-to-date check, rebuild when build settings or sources changed.
+	buildConfig := strings.Join(append(args, envList(env)...), "\n") + "\n"
+	configFile := filepath.Join(filepath.Dir(artifact), "."+filepath.Base(artifact)+".buildconfig")
+	upToDate, err := b.upToDate(artifact, configFile, buildConfig)
+	if err != nil {
+		return err
+	}
+	if upToDate {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(artifact), os.ModePerm); err != nil {
+		return fmt.Errorf("creating output directory: %w", err)
+	}
+	if err := sh.New(sh.WithWorkDir(b.workDir), sh.WithEnvironment(env)).Run(ctx, "go", args...); err != nil {
+		return fmt.Errorf("building %s for %s: %w", b.pkg, p, err)
+	}
+	if err := writeChecksum(artifact); err != nil {
+		return err
+	}
+	return os.WriteFile(configFile, []byte(buildConfig), 0o644)
+}
+
+func (b *GoBuild) buildArgs(p Platform, version, artifact string) (args []string, env map[string]string) {
+	env = map[string]string{"GOOS": p.OS, "GOARCH": p.Arch}
+	if b.cgo != nil {
+		env["CGO_ENABLED"] = "0"
+		if *b.cgo {
+			env["CGO_ENABLED"] = "1"
+		}
+	}
+
+	args = []string{"build", "-o", artifact}
+	if b.trimpath {
+		args = append(args, "-trimpath")
+	}
+	if len(b.tags) > 0 {
+		args = append(args, "-tags", strings.Join(b.tags, ","))
+	}
+	ldflags := append([]string{}, b.ldflags...)
+	for _, v := range b.versionVariables {
+		ldflags = append(ldflags, "-X", strconv.Quote(v+"="+version))
+	}
+	if len(ldflags) > 0 {
+		args = append(args, "-ldflags", strings.Join(ldflags, " "))
+	}
+	return append(args, b.pkg), env
+}
+
+// Checks whether artifact and checksum exist, were built with the same config and are newer than all sources.
+func (b *GoBuild) upToDate(artifact, configFile, buildConfig string) (bool, error) {
+	existingConfig, err := os.ReadFile(configFile)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if string(existingConfig) != buildConfig {
+		return false, nil
+	}
+
+	for _, f := range []string{artifact, artifact + ".sha256"} {
+		if _, err := os.Stat(f); os.IsNotExist(err) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+	}
+	artifactInfo, err := os.Stat(artifact)
+	if err != nil {
+		return false, err
+	}
+	newest, err := newestSource(b.workDir)
+	if err != nil {
+		return false, err
+	}
+	return !newest.After(artifactInfo.ModTime()), nil
+}
+
+// Returns the newest modification time of Go sources and module files below dir.
+// Hidden directories, like the cache directory, are skipped.
+func newestSource(dir string) (time.Time, error) {
+	if len(dir) == 0 {
+		dir = "."
+	}
+	var newest time.Time
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(p, ".go") && d.Name() != "go.mod" && d.Name() != "go.sum" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return newest, fmt.Errorf("checking sources: %w", err)
+	}
+	return newest, nil
+}
+
+// Writes a sha256sum compatible checksum file next to the artifact.
+func writeChecksum(artifact string) error {
+	f, err := os.Open(artifact)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("hashing %s: %w", artifact, err)
+	}
+	line := hex.EncodeToString(h.Sum(nil)) + "  " + filepath.Base(artifact) + "\n"
+	return os.WriteFile(artifact+".sha256", []byte(line), 0o644)
+}
+
+func envList(env map[string]string) []string {
+	keys := slices.Sorted(maps.Keys(env))
+	list := make([]string, len(keys))
+	for i, k := range keys {
+		list[i] = k + "=" + env[k]
+	}
+	return list
+}
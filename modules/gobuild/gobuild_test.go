@@ -0,0 +1,115 @@
+package gobuild
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pkg.package-operator.run/cardboard/run"
+)
+
+func TestGoBuild_ID(t *testing.T) {
+	t.Parallel()
+
+	mgr := run.New()
+	plain := NewGoBuild(mgr, "./cmd/tool")
+	assert.Equal(t, "pkg.package-operator.run/cardboard/modules/gobuild.GoBuild{pkg:./cmd/tool,name:tool,workDir:,"+
+		"outputDir:,platforms:["+HostPlatform().String()+"],versionVariables:[],ldflags:[],tags:[],trimpath:false,"+
+		"cgo:default}", plain.ID())
+
+	ids := map[string]struct{}{plain.ID(): {}}
+	for _, opt := range []Option{
+		WithName("other"),
+		WithWorkDir("sub"),
+		WithOutputDir("bin"),
+		WithPlatforms{{OS: "windows", Arch: "arm64"}},
+		WithVersionVariables{"main.version"},
+		WithLDFlags{"-s", "-w"},
+		WithTags{"netgo"},
+		WithTrimpath{},
+		WithCGO(false),
+	} {
+		id := NewGoBuild(mgr, "./cmd/tool", opt).ID()
+		assert.NotContains(t, ids, id, "%T changes the ID", opt)
+		ids[id] = struct{}{}
+	}
+}
+
+func TestGoBuild_buildArgs(t *testing.T) {
+	t.Parallel()
+
+	b := NewGoBuild(run.New(), "./cmd/tool",
+		WithVersionVariables{"main.version"}, WithLDFlags{"-s", "-w"},
+		WithTags{"netgo", "osusergo"}, WithTrimpath{}, WithCGO(false))
+	args, env := b.buildArgs(Platform{OS: "linux", Arch: "arm64"}, "v1.0.0-dirty", "/out/tool_linux_arm64")
+	assert.Equal(t, []string{
+		"build", "-o", "/out/tool_linux_arm64", "-trimpath", "-tags", "netgo,osusergo",
+		"-ldflags", `-s -w -X "main.version=v1.0.0-dirty"`, "./cmd/tool",
+	}, args)
+	assert.Equal(t, map[string]string{"GOOS": "linux", "GOARCH": "arm64", "CGO_ENABLED": "0"}, env)
+	assert.Equal(t, []string{"CGO_ENABLED=0", "GOARCH=arm64", "GOOS=linux"}, envList(env))
+}
+
+func TestGoBuild_Artifact(t *testing.T) {
+	t.Parallel()
+
+	out := t.TempDir()
+	b := NewGoBuild(run.New(), "./cmd/tool", WithOutputDir(out))
+	artifact, err := b.Artifact(Platform{OS: "linux", Arch: "amd64"})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(out, "tool_linux_amd64"), artifact)
+
+	artifact, err = b.Artifact(Platform{OS: "windows", Arch: "amd64"})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(out, "tool_windows_amd64.exe"), artifact)
+}
+
+func TestGoBuild_Build(t *testing.T) {
+	t.Parallel()
+
+	src := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(src, "go.mod"), []byte("module example.com/hello\n"), 0o600))
+	main := filepath.Join(src, "main.go")
+	require.NoError(t, os.WriteFile(main, []byte("package main\n\nfunc main() {}\n"), 0o600))
+
+	out := t.TempDir()
+	platforms := WithPlatforms{{OS: "linux", Arch: "amd64"}, {OS: "windows", Arch: "arm64"}}
+	b := NewGoBuild(run.New(), ".", WithName("hello"), WithWorkDir(src), WithOutputDir(out), platforms)
+	require.NoError(t, b.Build(t.Context()))
+
+	for _, p := range platforms {
+		artifact, err := b.Artifact(p)
+		require.NoError(t, err)
+		assert.FileExists(t, artifact)
+		sum, err := os.ReadFile(artifact + ".sha256")
+		require.NoError(t, err)
+		assert.Regexp(t, `^[0-9a-f]{64}  `+filepath.Base(artifact)+"\n$", string(sum))
+	}
+
+	artifact, err := b.Artifact(platforms[0])
+	require.NoError(t, err)
+	args, env := b.buildArgs(platforms[0], "", artifact)
+	configFile := filepath.Join(out, ".hello_linux_amd64.buildconfig")
+	config, err := os.ReadFile(configFile)
+	require.NoError(t, err)
+
+	upToDate, err := b.upToDate(artifact, configFile, string(config))
+	require.NoError(t, err)
+	assert.True(t, upToDate)
+	assert.Equal(t, strings.Join(append(args, envList(env)...), "\n")+"\n", string(config))
+
+	upToDate, err = b.upToDate(artifact, configFile, "other\n")
+	require.NoError(t, err)
+	assert.False(t, upToDate, "changed build config")
+
+	newer := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(main, newer, newer))
+	upToDate, err = b.upToDate(artifact, configFile, string(config))
+	require.NoError(t, err)
+	assert.False(t, upToDate, "changed sources")
+}
@@ -0,0 +1,70 @@
+package gobuild
+
+type Option interface {
+	ApplyToGoBuild(b *GoBuild)
+}
+
+// Name of the binary, defaults to the last element of the package path.
+type WithName string
+
+func (n WithName) ApplyToGoBuild(b *GoBuild) {
+	b.name = string(n)
+}
+
+// Directory to run go build in, defaults to the current working directory.
+type WithWorkDir string
+
+func (wd WithWorkDir) ApplyToGoBuild(b *GoBuild) {
+	b.workDir = string(wd)
+}
+
+// Directory to write artifacts to, defaults to "gobuild/<name>" in the cardboard cache directory.
+type WithOutputDir string
+
+func (od WithOutputDir) ApplyToGoBuild(b *GoBuild) {
+	b.outputDir = string(od)
+}
+
+// Platforms to build for, defaults to the host platform.
+type WithPlatforms []Platform
+
+func (p WithPlatforms) ApplyToGoBuild(b *GoBuild) {
+	b.platforms = p
+}
+
+// Fully qualified string variables to set to the output of "git describe" via -ldflags -X,
+// e.g. "main.version" or "example.com/project/internal/version.Version".
+type WithVersionVariables []string
+
+func (v WithVersionVariables) ApplyToGoBuild(b *GoBuild) {
+	b.versionVariables = v
+}
+
+// Additional flags passed via -ldflags.
+type WithLDFlags []string
+
+func (f WithLDFlags) ApplyToGoBuild(b *GoBuild) {
+	b.ldflags = f
+}
+
+// Build tags passed via -tags.
+type WithTags []string
+
+func (t WithTags) ApplyToGoBuild(b *GoBuild) {
+	b.tags = t
+}
+
+// Remove file system paths from the resulting binaries.
+type WithTrimpath struct{}
+
+func (WithTrimpath) ApplyToGoBuild(b *GoBuild) {
+	b.trimpath = true
+}
+
+// Set CGO_ENABLED, uses the go default if not set.
+type WithCGO bool
+
+func (c WithCGO) ApplyToGoBuild(b *GoBuild) {
+	enabled := bool(c)
+	b.cgo = &enabled
+}
@@ -0,0 +1,70 @@
+package gotest
+
+import (
+	"bufio"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Merges coverage profiles written by "go test -coverprofile" into a single profile.
+// Counts of blocks covered by multiple profiles are added up, or combined for mode "set".
+func mergeCoverProfiles(dst string, profiles ...string) error {
+	var mode string
+	blocks := map[string]int{}
+	for _, profile := range profiles {
+		f, err := os.Open(profile)
+		if os.IsNotExist(err) {
+			// Modules without test files produce no profile.
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("reading coverage profile: %w", err)
+		}
+
+		scanner := bufio.NewScanner(f)
+		for line := 1; scanner.Scan(); line++ {
+			text := scanner.Text()
+			if m, ok := strings.CutPrefix(text, "mode: "); ok {
+				if len(mode) > 0 && m != mode {
+					f.Close()
+					return fmt.Errorf("%s: coverage mode %q does not match %q", profile, m, mode)
+				}
+				mode = m
+				continue
+			}
+			if len(text) == 0 {
+				continue
+			}
+			// file:startLine.startCol,endLine.endCol numStatements count
+			i := strings.LastIndex(text, " ")
+			count, err := strconv.Atoi(text[i+1:])
+			if i < 0 || err != nil {
+				f.Close()
+				return fmt.Errorf("%s:%d: invalid coverage line %q", profile, line, text)
+			}
+			if block := text[:i]; mode == "set" {
+				blocks[block] = max(blocks[block], count)
+			} else {
+				blocks[block] += count
+			}
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("reading coverage profile: %w", err)
+		}
+	}
+	if len(mode) == 0 {
+		mode = "set"
+	}
+
+	var b strings.Builder
+	b.WriteString("mode: " + mode + "\n")
+	for _, block := range slices.Sorted(maps.Keys(blocks)) {
+		fmt.Fprintf(&b, "%s %d\n", block, blocks[block])
+	}
+	return os.WriteFile(dst, []byte(b.String()), 0o644)
+}
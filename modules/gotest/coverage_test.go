@@ -0,0 +1,76 @@
+package gotest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_mergeCoverProfiles(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		profiles []string
+		want     string
+		err      string
+	}{
+		{
+			name:     "count adds up",
+			profiles: []string{"cover-count-pass.txt", "cover-count-zero.txt"},
+			want: "mode: count\n" +
+				"example.com/fixture/a/a.go:4.2,4.12 1 3\n" +
+				"example.com/fixture/a/a.go:5.3,6.1 1 1\n" +
+				"example.com/fixture/a/a.go:7.2,7.14 1 2\n",
+		},
+		{
+			name:     "set combines",
+			profiles: []string{"cover-set-pass.txt", "cover-set-zero.txt"},
+			want: "mode: set\n" +
+				"example.com/fixture/a/a.go:4.2,4.12 1 1\n" +
+				"example.com/fixture/a/a.go:5.3,6.1 1 1\n" +
+				"example.com/fixture/a/a.go:7.2,7.14 1 1\n",
+		},
+		{
+			name:     "missing profiles are skipped",
+			profiles: []string{"cover-set-pass.txt", "missing.txt"},
+			want: "mode: set\n" +
+				"example.com/fixture/a/a.go:4.2,4.12 1 1\n" +
+				"example.com/fixture/a/a.go:5.3,6.1 1 0\n" +
+				"example.com/fixture/a/a.go:7.2,7.14 1 1\n",
+		},
+		{
+			name:     "no profiles",
+			profiles: []string{"missing.txt"},
+			want:     "mode: set\n",
+		},
+		{
+			name:     "mode mismatch",
+			profiles: []string{"cover-count-pass.txt", "cover-set-pass.txt"},
+			err:      `coverage mode "set" does not match "count"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			profiles := make([]string, len(test.profiles))
+			for i, p := range test.profiles {
+				profiles[i] = filepath.Join("testdata", p)
+			}
+			dst := filepath.Join(t.TempDir(), "cover.txt")
+			err := mergeCoverProfiles(dst, profiles...)
+			if len(test.err) > 0 {
+				require.ErrorContains(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			got, err := os.ReadFile(dst)
+			require.NoError(t, err)
+			assert.Equal(t, test.want, string(got))
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package gotest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// A single event emitted by "go test -json", see "go doc test2json".
+type TestEvent struct {
+	Time        time.Time
+	Action      string
+	Package     string
+	Test        string
+	Elapsed     float64
+	Output      string
+	FailedBuild string
+	// Set on build-output and build-fail events.
+	ImportPath string
+}
+
+// Outcome of a single test or of a whole package, when Test is empty.
+type TestResult struct {
+	Package string
+	Test    string
+	// One of "pass", "fail" or "skip".
+	Action  string
+	Elapsed time.Duration
+	// Combined output of the test.
+	Output string
+}
+
+func (r TestResult) ID() string {
+	if len(r.Test) == 0 {
+		return r.Package
+	}
+	return r.Package + "." + r.Test
+}
+
+// Collects results from a "go test -json" event stream.
+type results struct {
+	// Test and package results in the order they finished.
+	results []TestResult
+	// output by package and test, until the result is known.
+	output map[[2]string]*strings.Builder
+	// build output by import path.
+	buildOutput map[string]*strings.Builder
+}
+
+func newResults() *results {
+	return &results{
+		output:      map[[2]string]*strings.Builder{},
+		buildOutput: map[string]*strings.Builder{},
+	}
+}
+
+// Reads "go test -json" events from r.
+// Lines that are no JSON events, e.g. from build failures, are attributed to the package build output.
+func (rs *results) read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		var e TestEvent
+		if len(line) == 0 || line[0] != '{' || json.Unmarshal(line, &e) != nil {
+			rs.buildOutputFor("").WriteString(string(line) + "\n")
+			continue
+		}
+		rs.add(e)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading test events: %w", err)
+	}
+	return nil
+}
+
+func (rs *results) add(e TestEvent) {
+	key := [2]string{e.Package, e.Test}
+	switch e.Action {
+	case "build-output":
+		rs.buildOutputFor(e.ImportPath).WriteString(e.Output)
+	case "output":
+		b, ok := rs.output[key]
+		if !ok {
+			b = &strings.Builder{}
+			rs.output[key] = b
+		}
+		b.WriteString(e.Output)
+	case "pass", "fail", "skip":
+		var output string
+		if b, ok := rs.output[key]; ok {
+			output = b.String()
+			delete(rs.output, key)
+		}
+		if len(e.FailedBuild) > 0 {
+			output = rs.buildOutputFor(e.FailedBuild).String() + output
+		}
+		rs.results = append(rs.results, TestResult{
+			Package: e.Package,
+			Test:    e.Test,
+			Action:  e.Action,
+			Elapsed: time.Duration(e.Elapsed * float64(time.Second)),
+			Output:  output,
+		})
+	}
+}
+
+func (rs *results) buildOutputFor(importPath string) *strings.Builder {
+	b, ok := rs.buildOutput[importPath]
+	if !ok {
+		b = &strings.Builder{}
+		rs.buildOutput[importPath] = b
+	}
+	return b
+}
+
+// Returns failed tests, or failed packages without failed tests, e.g. due to build errors.
+func failures(results []TestResult) []TestResult {
+	failedTestsByPkg := map[string]bool{}
+	for _, r := range results {
+		if r.Action == "fail" && len(r.Test) > 0 {
+			failedTestsByPkg[r.Package] = true
+		}
+	}
+	var failed []TestResult
+	for _, r := range results {
+		if r.Action != "fail" || (len(r.Test) == 0 && failedTestsByPkg[r.Package]) {
+			continue
+		}
+		failed = append(failed, r)
+	}
+	slices.SortStableFunc(failed, func(a, b TestResult) int { return strings.Compare(a.ID(), b.ID()) })
+	return failed
+}
@@ -0,0 +1,99 @@
+package gotest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Reads results from a "go test -json" fixture in testdata.
+func readFixture(t *testing.T, name string) []TestResult {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	defer f.Close()
+	rs := newResults()
+	require.NoError(t, rs.read(f))
+	return rs.results
+}
+
+func Test_results_read(t *testing.T) {
+	t.Parallel()
+
+	const (
+		pkgA      = "example.com/fixture/a"
+		pkgBroken = "example.com/fixture/broken"
+	)
+	tests := []struct {
+		fixture  string
+		results  []TestResult
+		failures []string
+	}{
+		{
+			fixture: "tests.jsonl",
+			results: []TestResult{
+				{Package: pkgA, Test: "TestPass", Action: "pass", Output: "=== RUN   TestPass\n--- PASS: TestPass (0.00s)\n"},
+				{
+					Package: pkgA, Test: "TestFail", Action: "fail",
+					Output: "=== RUN   TestFail\n    a_test.go:12: checking\n    a_test.go:13: expected failure\n" +
+						"--- FAIL: TestFail (0.00s)\n",
+				},
+				{
+					Package: pkgA, Test: "TestSkip", Action: "skip",
+					Output: "=== RUN   TestSkip\n    a_test.go:17: not today\n--- SKIP: TestSkip (0.00s)\n",
+				},
+				{
+					Package: pkgA, Test: "TestSub/ok", Action: "pass",
+					Output: "=== RUN   TestSub/ok\n--- PASS: TestSub/ok (0.00s)\n",
+				},
+				{Package: pkgA, Test: "TestSub", Action: "pass", Output: "=== RUN   TestSub\n--- PASS: TestSub (0.00s)\n"},
+				{
+					Package: pkgA, Action: "fail", Elapsed: 2 * time.Millisecond,
+					Output: "FAIL\nFAIL\texample.com/fixture/a\t0.002s\n",
+				},
+				{Package: "example.com/fixture/b", Action: "skip", Output: "?   \texample.com/fixture/b\t[no test files]\n"},
+			},
+			failures: []string{pkgA + ".TestFail"},
+		},
+		{
+			fixture: "build-fail.jsonl",
+			results: []TestResult{
+				{
+					Package: pkgBroken, Action: "fail",
+					Output: "# example.com/fixture/broken [example.com/fixture/broken.test]\n" +
+						"broken/broken.go:4:9: cannot use \"nope\" (untyped string constant) as int value in return statement\n" +
+						"FAIL\texample.com/fixture/broken [build failed]\n",
+				},
+			},
+			failures: []string{pkgBroken},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.fixture, func(t *testing.T) {
+			t.Parallel()
+
+			results := readFixture(t, test.fixture)
+			assert.Equal(t, test.results, results)
+			var failed []string
+			for _, r := range failures(results) {
+				failed = append(failed, r.ID())
+			}
+			assert.Equal(t, test.failures, failed)
+		})
+	}
+}
+
+func Test_results_read_plainOutput(t *testing.T) {
+	t.Parallel()
+
+	rs := newResults()
+	require.NoError(t, rs.read(strings.NewReader("go: downloading example.com/dep v1.0.0\n")))
+	assert.Empty(t, rs.results)
+	assert.Equal(t, "go: downloading example.com/dep v1.0.0\n", rs.buildOutputFor("").String())
+}
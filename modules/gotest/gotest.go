@@ -0,0 +1,279 @@
+// Package gotest runs go test across all modules of a workspace and reports structured results.
+package gotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"pkg.package-operator.run/cardboard/cache"
+	"pkg.package-operator.run/cardboard/run"
+	"pkg.package-operator.run/cardboard/sh"
+	"pkg.package-operator.run/cardboard/workspace"
+)
+
+// Runs go test for all modules of a go.work workspace or a single module.
+type GoTest struct {
+	mgr                   *run.Manager
+	workDir, outputDir    string
+	args                  []string
+	changedSince          string
+	race, coverage, junit bool
+	out                   io.Writer
+
+	resultsMux sync.Mutex
+	// module directory -> results.
+	results map[string][]TestResult
+}
+
+// Creates a new GoTest.
+func NewGoTest(mgr *run.Manager, opts ...Option) *GoTest {
+	t := &GoTest{
+		mgr:     mgr,
+		out:     os.Stdout,
+		results: map[string][]TestResult{},
+	}
+	for _, opt := range opts {
+		opt.ApplyToGoTest(t)
+	}
+	return t
+}
+
+func (t *GoTest) ID() string {
+	return fmt.Sprintf("pkg.package-operator.run/cardboard/modules/gotest.GoTest{workDir:%s}", t.workDir)
+}
+
+// Returns a Test dependency.
+func (t *GoTest) Run(ctx context.Context) error {
+	return t.Test(ctx)
+}
+
+// Tests all modules in parallel, prints a summary and writes the configured reports.
+func (t *GoTest) Test(ctx context.Context) error {
+	modules, err := t.modulePackages(ctx)
+	if err != nil {
+		return err
+	}
+	outputDir, err := t.OutputDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+		return fmt.Errorf("creating output directory: %w", err)
+	}
+
+	deps := make([]run.Dependency, len(modules))
+	for i, module := range modules {
+		deps[i] = run.Meth2(t, t.testModule, module.dir, module.packages)
+	}
+	testErr := t.mgr.ParallelDeps(ctx, t, deps...)
+
+	var (
+		all      []TestResult
+		profiles []string
+	)
+	for _, module := range modules {
+		results, ran := t.results[module.dir]
+		if !ran {
+			// Profiles of modules not tested in this run are outdated.
+			continue
+		}
+		all = append(all, results...)
+		profiles = append(profiles, t.coverProfile(outputDir, module.dir))
+	}
+	errs := []error{testErr, printSummary(t.out, all)}
+	if t.junit {
+		errs = append(errs, writeJUnit(filepath.Join(outputDir, "junit.xml"), all))
+	}
+	if t.coverage {
+		errs = append(errs, mergeCoverProfiles(filepath.Join(outputDir, "cover.txt"), profiles...))
+	}
+	return errors.Join(errs...)
+}
+
+// Returns the directory reports are written to.
+func (t *GoTest) OutputDir() (string, error) {
+	if len(t.outputDir) > 0 {
+		return filepath.Abs(t.outputDir)
+	}
+	return cache.Path("gotest")
+}
+
+func (t *GoTest) coverProfile(outputDir, module string) string {
+	name := strings.NewReplacer("/", "_", ".", "_").Replace(filepath.ToSlash(filepath.Clean(module)))
+	if name == "_" {
+		name = "root"
+	}
+	return filepath.Join(outputDir, "coverage", name+".txt")
+}
+
+func (t *GoTest) testModule(ctx context.Context, module string, packages []string) error {
+	outputDir, err := t.OutputDir()
+	if err != nil {
+		return err
+	}
+
+	args := []string{"test", "-json"}
+	env := map[string]string{}
+	if t.race {
+		args = append(args, "-race")
+		env["CGO_ENABLED"] = "1"
+	}
+	if t.coverage {
+		profile := t.coverProfile(outputDir, module)
+		if err := os.MkdirAll(filepath.Dir(profile), os.ModePerm); err != nil {
+			return fmt.Errorf("creating coverage directory: %w", err)
+		}
+		// A profile of an earlier run must not be merged, if this run fails to write one.
+		if err := os.Remove(profile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("removing coverage profile: %w", err)
+		}
+		args = append(args, "-coverprofile="+profile)
+	}
+	args = append(args, t.args...)
+	args = append(args, packages...)
+
+	out, runErr := sh.New(
+		sh.WithWorkDir(filepath.Join(t.workDir, module)),
+		sh.WithEnvironment(env),
+	).Output(ctx, "go", args...)
+
+	rs := newResults()
+	if err := rs.read(strings.NewReader(out)); err != nil {
+		return err
+	}
+	t.resultsMux.Lock()
+	t.results[module] = rs.results
+	t.resultsMux.Unlock()
+
+	if failed := failures(rs.results); len(failed) > 0 {
+		return &TestFailuresError{Module: module, Failures: failed}
+	}
+	if runErr != nil {
+		return fmt.Errorf("testing module %s: %w\n%s", module, runErr, rs.buildOutputFor("").String())
+	}
+	return nil
+}
+
+type modulePackages struct {
+	dir      string
+	packages []string
+}
+
+// Returns the packages to test per module directory.
+// All packages of all workspace modules or only affected packages, if WithChangedSince is set.
+func (t *GoTest) modulePackages(ctx context.Context) ([]modulePackages, error) {
+	workDir := t.workDir
+	if len(workDir) == 0 {
+		workDir = "."
+	}
+	ws, err := workspace.Load(workDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(t.changedSince) == 0 {
+		modules := make([]modulePackages, len(ws.Modules))
+		for i, m := range ws.Modules {
+			modules[i] = modulePackages{dir: m.Dir, packages: []string{"./..."}}
+		}
+		return modules, nil
+	}
+
+	files, err := ws.ChangedFiles(ctx, t.changedSince)
+	if err != nil {
+		return nil, err
+	}
+	pkgs, err := ws.AffectedPackages(ctx, files)
+	if err != nil {
+		return nil, err
+	}
+	var modules []modulePackages
+	for _, m := range ws.Modules {
+		mp := modulePackages{dir: m.Dir}
+		for _, p := range pkgs {
+			if p.Module == m.Path {
+				mp.packages = append(mp.packages, p.ImportPath)
+			}
+		}
+		if len(mp.packages) > 0 {
+			modules = append(modules, mp)
+		}
+	}
+	return modules, nil
+}
+
+// TestFailuresError is returned when tests of a module failed.
+type TestFailuresError struct {
+	Module   string
+	Failures []TestResult
+}
+
+func (e *TestFailuresError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d tests failed in module %s:", len(e.Failures), e.Module)
+	for _, f := range e.Failures {
+		fmt.Fprintf(&b, "\n--- FAIL: %s (%s)\n%s", f.ID(), f.Elapsed, strings.TrimRight(f.Output, "\n"))
+	}
+	return b.String()
+}
+
+// Prints a result line per package and totals.
+func printSummary(out io.Writer, results []TestResult) error {
+	type pkgSummary struct {
+		action                  string
+		elapsed                 time.Duration
+		passed, failed, skipped int
+	}
+	var (
+		pkgs    []string
+		summary = map[string]*pkgSummary{}
+		total   pkgSummary
+	)
+	for _, r := range results {
+		s, ok := summary[r.Package]
+		if !ok {
+			s = &pkgSummary{}
+			summary[r.Package] = s
+			pkgs = append(pkgs, r.Package)
+		}
+		if len(r.Test) == 0 {
+			s.action, s.elapsed = r.Action, r.Elapsed
+			total.elapsed += r.Elapsed
+			continue
+		}
+		switch r.Action {
+		case "pass":
+			s.passed++
+			total.passed++
+		case "fail":
+			s.failed++
+			total.failed++
+		case "skip":
+			s.skipped++
+			total.skipped++
+		}
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, pkg := range pkgs {
+		s := summary[pkg]
+		status := map[string]string{"pass": "ok", "fail": "FAIL", "skip": "-"}[s.action]
+		if s.action != "fail" && s.passed+s.failed+s.skipped == 0 {
+			fmt.Fprintf(w, "%s\t%s\t[no test files]\n", status, pkg)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d passed, %d failed, %d skipped\n",
+			status, pkg, s.elapsed.Round(time.Millisecond), s.passed, s.failed, s.skipped)
+	}
+	fmt.Fprintf(w, "\nDONE\t%d tests\t%s\t%d passed, %d failed, %d skipped\n",
+		total.passed+total.failed+total.skipped, total.elapsed.Round(time.Millisecond),
+		total.passed, total.failed, total.skipped)
+	return w.Flush()
+}
@@ -0,0 +1,28 @@
+package gotest
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pkg.package-operator.run/cardboard/run"
+)
+
+func TestGoTest_testModule_removesStaleCoverProfile(t *testing.T) {
+	t.Parallel()
+
+	out := t.TempDir()
+	// go test does not start and write a new profile for a missing module directory.
+	missing := filepath.Join(t.TempDir(), "missing")
+	gt := NewGoTest(run.New(), WithWorkDir(missing), WithOutputDir(out), WithCoverage{}, WithOutput{io.Discard})
+	profile := gt.coverProfile(out, ".")
+	require.NoError(t, os.MkdirAll(filepath.Dir(profile), os.ModePerm))
+	require.NoError(t, os.WriteFile(profile, []byte("mode: set\nexample.com/stale/stale.go:3.10,3.11 1 1\n"), 0o600))
+
+	require.Error(t, gt.testModule(t.Context(), ".", []string{"./..."}))
+	assert.NoFileExists(t, profile)
+}
@@ -0,0 +1,90 @@
+package gotest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"time"
+)
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// Writes test results as JUnit XML report, with one test suite per package.
+func writeJUnit(file string, results []TestResult) error {
+	suites := map[string]*junitTestSuite{}
+	for _, r := range results {
+		suite, ok := suites[r.Package]
+		if !ok {
+			suite = &junitTestSuite{Name: r.Package}
+			suites[r.Package] = suite
+		}
+		if len(r.Test) == 0 {
+			suite.Time = junitTime(r.Elapsed)
+			if r.Action != "fail" || slices.ContainsFunc(results, func(o TestResult) bool {
+				return o.Package == r.Package && len(o.Test) > 0 && o.Action == "fail"
+			}) {
+				continue
+			}
+			// Package failed without failing tests, e.g. build errors or panics in init.
+		}
+
+		tc := junitTestCase{Name: r.Test, ClassName: r.Package, Time: junitTime(r.Elapsed)}
+		if len(tc.Name) == 0 {
+			tc.Name = "package"
+		}
+		suite.Tests++
+		switch r.Action {
+		case "fail":
+			suite.Failures++
+			tc.Failure = &junitMessage{Message: "Failed", Body: r.Output}
+		case "skip":
+			suite.Skipped++
+			tc.Skipped = &junitMessage{Message: "Skipped", Body: r.Output}
+		default:
+			tc.SystemOut = r.Output
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+
+	var doc junitTestSuites
+	for _, name := range slices.Sorted(maps.Keys(suites)) {
+		doc.Suites = append(doc.Suites, *suites[name])
+	}
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding JUnit report: %w", err)
+	}
+	return os.WriteFile(file, append([]byte(xml.Header), data...), 0o644)
+}
+
+func junitTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
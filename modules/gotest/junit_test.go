@@ -0,0 +1,35 @@
+package gotest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_writeJUnit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		fixture, golden string
+	}{
+		{fixture: "tests.jsonl", golden: "tests.junit.xml"},
+		// Packages failing to build are reported as a failed "package" test case.
+		{fixture: "build-fail.jsonl", golden: "build-fail.junit.xml"},
+	}
+	for _, test := range tests {
+		t.Run(test.fixture, func(t *testing.T) {
+			t.Parallel()
+
+			file := filepath.Join(t.TempDir(), "junit.xml")
+			require.NoError(t, writeJUnit(file, readFixture(t, test.fixture)))
+			got, err := os.ReadFile(file)
+			require.NoError(t, err)
+			want, err := os.ReadFile(filepath.Join("testdata", test.golden))
+			require.NoError(t, err)
+			assert.Equal(t, string(want), string(got))
+		})
+	}
+}
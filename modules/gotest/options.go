@@ -0,0 +1,64 @@
+package gotest
+
+import "io"
+
+type Option interface {
+	ApplyToGoTest(t *GoTest)
+}
+
+// Directory containing the go.work or go.mod file, defaults to the current working directory.
+type WithWorkDir string
+
+func (wd WithWorkDir) ApplyToGoTest(t *GoTest) {
+	t.workDir = string(wd)
+}
+
+// Directory to write reports to, defaults to "gotest" in the cardboard cache directory.
+type WithOutputDir string
+
+func (od WithOutputDir) ApplyToGoTest(t *GoTest) {
+	t.outputDir = string(od)
+}
+
+// Additional arguments passed to go test, e.g. "-run=TestX" or "-count=1".
+type WithArgs []string
+
+func (a WithArgs) ApplyToGoTest(t *GoTest) {
+	t.args = a
+}
+
+// Enables the race detector.
+type WithRace struct{}
+
+func (WithRace) ApplyToGoTest(t *GoTest) {
+	t.race = true
+}
+
+// Collects coverage profiles and merges them into cover.txt in the output directory.
+type WithCoverage struct{}
+
+func (WithCoverage) ApplyToGoTest(t *GoTest) {
+	t.coverage = true
+}
+
+// Writes a JUnit XML report to junit.xml in the output directory.
+type WithJUnit struct{}
+
+func (WithJUnit) ApplyToGoTest(t *GoTest) {
+	t.junit = true
+}
+
+// Writer to print the summary to, defaults to os.Stdout.
+type WithOutput struct{ io.Writer }
+
+func (o WithOutput) ApplyToGoTest(t *GoTest) {
+	t.out = o.Writer
+}
+
+// Only tests packages affected by changes since the given git ref, e.g. "origin/main".
+// Modules without affected packages are skipped.
+type WithChangedSince string
+
+func (cs WithChangedSince) ApplyToGoTest(t *GoTest) {
+	t.changedSince = string(cs)
+}
@@ -9,6 +9,7 @@ replace (
 )
 
 require (
+	pkg.package-operator.run/cardboard v0.0.4
 	pkg.package-operator.run/cardboard/kubeutils v0.0.4
 	pkg.package-operator.run/cardboard/modules/kubeclients v0.0.4
 	sigs.k8s.io/controller-runtime v0.24.1
@@ -15,16 +15,14 @@ import (
 	kindcmd "sigs.k8s.io/kind/pkg/cmd"
 	"sigs.k8s.io/yaml"
 
+	"pkg.package-operator.run/cardboard/cache"
 	"pkg.package-operator.run/cardboard/kubeutils"
 	"pkg.package-operator.run/cardboard/modules/kubeclients"
 )
 
-const defaultCacheDirectory = ".cache"
-
 // Represents a KinD cluster.
 type Cluster struct {
-	name, workDir    string
-	kubeconfigPath   string
+	name             string
 	containerRuntime kubeutils.ContainerRuntime
 	clusterConfig    *kindv1alpha4.Cluster
 
@@ -40,10 +38,8 @@ type ClusterOption interface {
 
 func NewCluster(name string, opts ...ClusterOption) *Cluster {
 	kc := &Cluster{
-		name:    name,
-		workDir: filepath.Join(defaultCacheDirectory, "clusters", name),
+		name: name,
 	}
-	kc.kubeconfigPath = filepath.Join(kc.workDir, "kubeconfig.yaml")
 	for _, opt := range opts {
 		opt.ApplyToCluster(kc)
 	}
@@ -65,7 +61,16 @@ func (c *Cluster) Kubeconfig(internal bool) (string, error) {
 }
 
 func (c *Cluster) KubeconfigPath() (string, error) {
-	return filepath.Abs(c.kubeconfigPath)
+	workDir, err := c.WorkDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(workDir, "kubeconfig.yaml"), nil
+}
+
+// Returns the directory cluster files are stored in, within the shared cardboard cache directory.
+func (c *Cluster) WorkDir() (string, error) {
+	return cache.Path("clusters", c.name)
 }
 
 func (c *Cluster) ID() string {
@@ -103,7 +108,11 @@ func (c *Cluster) Clients() (*kubeclients.KubeClients, error) {
 	if c.clients != nil {
 		return c.clients, nil
 	}
-	c.clients = kubeclients.NewKubeClients(c.kubeconfigPath, c.kubeClientsOptions...)
+	kubeconfigPath, err := c.KubeconfigPath()
+	if err != nil {
+		return nil, err
+	}
+	c.clients = kubeclients.NewKubeClients(kubeconfigPath, c.kubeClientsOptions...)
 	if err := c.clients.Run(context.Background()); err != nil {
 		return nil, err
 	}
@@ -117,7 +126,15 @@ func (c *Cluster) Run(ctx context.Context) error {
 
 // Creates the KinD cluster if it does not exist.
 func (c *Cluster) Create(ctx context.Context) error {
-	if err := os.MkdirAll(c.workDir, os.ModePerm); err != nil {
+	workDir, err := c.WorkDir()
+	if err != nil {
+		return err
+	}
+	kubeconfigPath, err := c.KubeconfigPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(workDir, os.ModePerm); err != nil {
 		return fmt.Errorf("creating workdir: %w", err)
 	}
 	kindConfigYamlBytes, err := yaml.Marshal(c.clusterConfig)
@@ -125,7 +142,7 @@ func (c *Cluster) Create(ctx context.Context) error {
 		return fmt.Errorf("failed to process the KinD cluster config as a YAML: %w", err)
 	}
 
-	kindconfigPath := filepath.Join(c.workDir, "/kind.yaml")
+	kindconfigPath := filepath.Join(workDir, "kind.yaml")
 	if err := os.WriteFile(
 		kindconfigPath, kindConfigYamlBytes, os.ModePerm); err != nil {
 		return fmt.Errorf("creating kind cluster config: %w", err)
@@ -146,7 +163,7 @@ func (c *Cluster) Create(ctx context.Context) error {
 	}
 
 	if err := provider.Create(c.name,
-		cluster.CreateWithKubeconfigPath(c.kubeconfigPath),
+		cluster.CreateWithKubeconfigPath(kubeconfigPath),
 		cluster.CreateWithConfigFile(kindconfigPath),
 		cluster.CreateWithDisplayUsage(true),
 		cluster.CreateWithDisplaySalutation(true),
@@ -172,7 +189,11 @@ func (c *Cluster) Destroy(_ context.Context) error {
 	if err != nil {
 		return err
 	}
-	return provider.Delete(c.name, c.kubeconfigPath)
+	kubeconfigPath, err := c.KubeconfigPath()
+	if err != nil {
+		return err
+	}
+	return provider.Delete(c.name, kubeconfigPath)
 }
 
 // Load an image from a tar archive into the environment.
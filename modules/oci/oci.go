@@ -3,7 +3,11 @@ package oci
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 
+	"pkg.package-operator.run/cardboard/cache"
 	"pkg.package-operator.run/cardboard/kubeutils"
 	"pkg.package-operator.run/cardboard/sh"
 )
@@ -67,6 +71,20 @@ func (oci *OCI) ID() string {
 	return fmt.Sprintf("pkg.package-operator.run/cardboard/modules/oci.OCI{tag:%s}", oci.tag)
 }
 
+// Returns the directory build outputs are stored in, within the shared cardboard cache directory.
+func (oci *OCI) OutputDir() (string, error) {
+	return cache.Path("oci", strings.NewReplacer("/", "_", ":", "_", "@", "_").Replace(oci.tag))
+}
+
+// Returns the path of the image archive written by Build.
+func (oci *OCI) TarPath() (string, error) {
+	outputDir, err := oci.OutputDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(outputDir, ociTarFilename), nil
+}
+
 // Returns a Build dependency.
 func (oci *OCI) Run(ctx context.Context) error {
 	return oci.Build(ctx)
@@ -90,9 +108,16 @@ func (oci *OCI) Build(ctx context.Context) error {
 		return err
 	}
 
+	tarPath, err := oci.TarPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(tarPath), os.ModePerm); err != nil {
+		return fmt.Errorf("creating output directory: %w", err)
+	}
 	imgSaveArgs := []string{
 		"image", "save",
-		"-o", ociTarFilename, oci.tag,
+		"-o", tarPath, oci.tag,
 	}
 	if err := oci.runner.Run(ctx, string(cr), imgSaveArgs...); err != nil {
 		return err
@@ -109,7 +134,11 @@ func (oci *OCI) Push(ctx context.Context) error {
 }
 
 func (oci *OCI) pushWithCrane(ctx context.Context) error {
-	args := []string{"push", ociTarFilename, oci.tag}
+	tarPath, err := oci.TarPath()
+	if err != nil {
+		return err
+	}
+	args := []string{"push", tarPath, oci.tag}
 	if err := oci.runner.Run(ctx, "crane", args...); err != nil {
 		return err
 	}
@@ -125,7 +154,14 @@ func (oci *OCI) pushWithCR(ctx context.Context) error {
 
 	args := []string{"push"}
 	if cr == kubeutils.ContainerRuntimePodman {
-		args = append(args, "--digestfile="+ociDigestFile)
+		outputDir, err := oci.OutputDir()
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+			return fmt.Errorf("creating output directory: %w", err)
+		}
+		args = append(args, "--digestfile="+filepath.Join(outputDir, ociDigestFile))
 	}
 	args = append(args, oci.tag)
 	if err := oci.runner.Run(ctx, string(cr), args...); err != nil {
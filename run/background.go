@@ -0,0 +1,95 @@
+package run
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"pkg.package-operator.run/cardboard/sh"
+)
+
+// Lines of output per background process attached to the report.
+const backgroundReportLines = 20
+
+// Tracks background processes started via sh.Runner.Background while a target runs.
+type backgroundProcesses struct {
+	mux sync.Mutex
+	// processes still running.
+	running []*sh.Process
+	// processes stopped since the last report.
+	stopped []*sh.Process
+}
+
+func (b *backgroundProcesses) Track(p *sh.Process) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.running = append(b.running, p)
+}
+
+// Stops all running processes in reverse start order.
+func (b *backgroundProcesses) stopAll() error {
+	b.mux.Lock()
+	running := b.running
+	b.running = nil
+	b.mux.Unlock()
+
+	var errs []error
+	for i := len(running) - 1; i >= 0; i-- {
+		errs = append(errs, running[i].Stop())
+	}
+
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.stopped = append(b.stopped, running...)
+	return errors.Join(errs...)
+}
+
+// Report of a stopped background process.
+type backgroundReport struct {
+	Command string `json:"command"`
+	PID     int    `json:"pid"`
+	// Last lines of output.
+	Lines   []string `json:"lines"`
+	Omitted int      `json:"omittedLines,omitempty"`
+}
+
+// Returns reports of all processes stopped since the last call.
+func (b *backgroundProcesses) reports() []backgroundReport {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	reports := make([]backgroundReport, 0, len(b.stopped))
+	for _, p := range b.stopped {
+		r := backgroundReport{Command: p.String(), PID: p.PID(), Lines: p.Lines()}
+		if len(r.Lines) > backgroundReportLines {
+			r.Omitted = len(r.Lines) - backgroundReportLines
+			r.Lines = r.Lines[r.Omitted:]
+		}
+		reports = append(reports, r)
+	}
+	b.stopped = nil
+	return reports
+}
+
+// Writes the last lines of output of all stopped processes.
+func (b *backgroundProcesses) report(w io.Writer) error {
+	reports := b.reports()
+	if len(reports) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintln(w, "Background Processes:"); err != nil {
+		return err
+	}
+	for _, r := range reports {
+		fmt.Fprintf(w, "%s (pid %d)\n", r.Command, r.PID)
+		if r.Omitted > 0 {
+			fmt.Fprintf(w, "  ... %d lines omitted\n", r.Omitted)
+		}
+		for _, line := range r.Lines {
+			if _, err := fmt.Fprintf(w, "  %s\n", line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package run
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pkg.package-operator.run/cardboard/cache"
+	"pkg.package-operator.run/cardboard/sh"
+)
+
+type BackgroundThing struct {
+	process *sh.Process
+}
+
+func (b *BackgroundThing) Test(ctx context.Context, _ []string) error {
+	var err error
+	b.process, err = sh.New(sh.WithCombinedOutput{Writer: io.Discard}).Background(ctx,
+		sh.LogProbe{Pattern: regexp.MustCompile("listening")}, "bash", "-c", "echo listening; sleep 60")
+	return err
+}
+
+//nolint:paralleltest // modifies os.Args.
+func TestManager_Run_background(t *testing.T) {
+	// Run exports the cache directory, restore it afterwards.
+	t.Setenv(cache.DirEnv, t.TempDir())
+	var stderr bytes.Buffer
+	thing := &BackgroundThing{}
+	m := New(WithStderr{&stderr}, WithStdout{io.Discard})
+	require.NoError(t, m.Register(thing))
+
+	os.Args = []string{"", "BackgroundThing:Test"}
+	require.NoError(t, m.Run(t.Context()))
+
+	select {
+	case <-thing.process.Done():
+	default:
+		t.Fatal("background process still running")
+	}
+	assert.Regexp(t, `(?m)^Background Processes:\nbash -c echo listening; sleep 60 \(pid \d+\)\n  listening\n`,
+		stderr.String())
+}
+
+//nolint:paralleltest // modifies os.Args.
+func TestManager_Run_backgroundJSON(t *testing.T) {
+	t.Setenv(cache.DirEnv, t.TempDir())
+	var stderr bytes.Buffer
+	m := New(WithStderr{&stderr}, WithStdout{io.Discard}, WithReportFormat(ReportFormatJSON))
+	require.NoError(t, m.Register(&BackgroundThing{}))
+
+	os.Args = []string{"", "BackgroundThing:Test"}
+	require.NoError(t, m.Run(t.Context()))
+
+	var report struct {
+		Background []backgroundReport `json:"background"`
+	}
+	require.NoError(t, json.Unmarshal(stderr.Bytes(), &report))
+	require.Len(t, report.Background, 1)
+	assert.Equal(t, "bash -c echo listening; sleep 60", report.Background[0].Command)
+	assert.Equal(t, []string{"listening"}, report.Background[0].Lines)
+	assert.Empty(t, m.background.reports(), "reported processes are reset")
+}
@@ -2,50 +2,91 @@ package run
 
 import (
 	"context"
+	"debug/buildinfo"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"maps"
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"unicode"
 
+	"pkg.package-operator.run/cardboard/cache"
 	"pkg.package-operator.run/cardboard/sh"
 )
 
 // Manages binary dependencies in a project-local folder.
 type dependencyManager struct {
-	path   string
-	runner *sh.Runner
-	dr     *dependencyRun
-	depFns []Dependency
-	deps   map[string]string
+	// cache directory as configured.
+	cacheDir string
+	path     string
+	runner   *sh.Runner
+	logger   *slog.Logger
+	dr       *dependencyRun
+	depFns   []Dependency
+	// tool -> install dependency.
+	toolDeps map[string]Dependency
+	// tool -> package URL with version or download URL.
+	deps map[string]string
+	// tool -> version with "v" prefix.
+	versions map[string]string
+	// tools downloaded as prebuilt binaries.
+	downloads map[string]Binary
+	// tools declared in go.mod files, built within their module.
+	modTools map[string]goModTool
+	// verify installed tools against the lockfile.
+	verifyLock bool
+	// install tools without network access.
+	offline bool
+	// GOPROXY-layout directory to install go tools from in offline mode.
+	vendorDir string
+	// skip installing tools, commands are not executed in dry run mode.
+	dryRun bool
 }
 
 var _ Dependency = (*dependencyManager)(nil)
 
-const (
-	defaultCacheDirectory = ".cache"
-)
-
-func newDependencyManager(dr *dependencyRun) *dependencyManager {
-	absCacheDir, err := filepath.Abs(defaultCacheDirectory)
+func newDependencyManager(dr *dependencyRun, cacheDir string) (*dependencyManager, error) {
+	absCacheDir, err := cache.Resolve(cacheDir)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("resolving cache directory: %w", err)
 	}
+	return dependencyManagerAt(dr, cacheDir, absCacheDir), nil
+}
 
+// Creates a dependencyManager keeping tools in the deps folder of the given absolute cache directory.
+func dependencyManagerAt(dr *dependencyRun, cacheDir, absCacheDir string) *dependencyManager {
 	path := filepath.Join(absCacheDir, "deps")
 	dm := &dependencyManager{
-		path: path,
-		dr:   dr,
-		deps: map[string]string{},
+		cacheDir:  cacheDir,
+		path:      path,
+		dr:        dr,
+		logger:    slog.New(slog.DiscardHandler),
+		toolDeps:  map[string]Dependency{},
+		deps:      map[string]string{},
+		versions:  map[string]string{},
+		downloads: map[string]Binary{},
+		modTools:  map[string]goModTool{},
 	}
 	dm.runner = sh.New(sh.WithEnvironment{"GOBIN": dm.Bin()})
 	return dm
 }
 
 func (d *dependencyManager) ID() string {
-	return fmt.Sprintf("pkg.package-operator.run/cardboard/run.dependencyManager{path:%s}.Run()", defaultCacheDirectory)
+	return fmt.Sprintf("pkg.package-operator.run/cardboard/run.dependencyManager{path:%s}.Run()", d.cacheDir)
 }
 
 func (d *dependencyManager) Run(ctx context.Context) error {
+	if d.offline && !d.dryRun {
+		if err := d.checkOfflineAll(ctx); err != nil {
+			return err
+		}
+	}
 	return d.dr.Parallel(ctx, DependencyID(d.ID()), d.depFns...)
 }
 
@@ -70,70 +111,230 @@ func (d *dependencyManager) Register(ctx context.Context, tool, packageURL, vers
 	}
 
 	d.deps[tool] = newURL
-	d.depFns = append(d.depFns, FnWithName("go install "+tool, installFn))
+	d.versions[tool] = "v" + version
+	d.add(tool, FnWithName("go install "+tool, installFn))
 	return nil
 }
 
+func (d *dependencyManager) add(tool string, dep Dependency) {
+	if _, ok := d.toolDeps[tool]; ok {
+		return
+	}
+	// Verified after every install, so lazily installed tools are checked as well.
+	verified := FnWithName(dep.ID(), func(ctx context.Context) error {
+		if err := dep.Run(ctx); err != nil {
+			return err
+		}
+		return d.verifyLocked(tool)
+	})
+	d.toolDeps[tool] = verified
+	d.depFns = append(d.depFns, verified)
+}
+
+// Returns a dependency installing the given tool.
+func (d *dependencyManager) Tool(tool string) Dependency {
+	if dep, ok := d.toolDeps[tool]; ok {
+		return dep
+	}
+	return FnWithName("install "+tool, func() error {
+		return &UnknownToolError{Tool: tool}
+	})
+}
+
+// Installs the given command before it is executed, if it is a registered tool.
+// Scripts may run any registered tool, so all tools are installed before a shell is executed.
+// Used as sh.CommandHook when tools are installed lazily.
+func (d *dependencyManager) ensureTool(ctx context.Context, cmd string, args []string, script string) error {
+	if dep, ok := d.toolDeps[cmd]; ok {
+		return d.dr.Serial(ctx, DependencyID("."), dep)
+	}
+	if !slices.Contains(shells, path.Base(cmd)) {
+		return nil
+	}
+	// Scripts only install the tools they mention.
+	var deps []Dependency
+	for _, word := range scriptWords(append(slices.Clone(args), script)) {
+		if dep, ok := d.toolDeps[word]; ok && !slices.Contains(deps, dep) {
+			deps = append(deps, dep)
+		}
+	}
+	if len(deps) == 0 {
+		return nil
+	}
+	return d.dr.Serial(ctx, DependencyID("."), deps...)
+}
+
+// Commands running scripts, like sh.Runner.Bash.
+var shells = []string{"bash", "sh"}
+
+// Splits scripts into words that may name a tool, paths are split into their elements.
+func scriptWords(scripts []string) []string {
+	var words []string
+	for _, s := range scripts {
+		words = append(words, strings.FieldsFunc(s, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("-_.+", r)
+		})...)
+	}
+	return words
+}
+
+// Prints registered tools with their version, install state and path.
+func (d *dependencyManager) printTools(out io.Writer) error {
+	tools := slices.Sorted(maps.Keys(d.deps))
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TOOL\tVERSION\tSTATE\tPATH\tSOURCE")
+	for _, tool := range tools {
+		version := strings.TrimPrefix(d.versions[tool], "v")
+		var (
+			reason string
+			err    error
+		)
+		if _, ok := d.downloads[tool]; ok {
+			reason, err = d.downloadReason(tool, version)
+		} else {
+			reason = d.goToolReason(tool)
+		}
+		if err != nil {
+			return err
+		}
+		state := "installed"
+		if len(reason) > 0 {
+			state = reason
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", tool, d.versions[tool], state, path.Join(d.Bin(), tool), d.deps[tool])
+	}
+	return w.Flush()
+}
+
 // go install a dependency into the dependency directory.
 func (d *dependencyManager) goInstall(ctx context.Context, tool, packageURL, version string) error {
-	if err := os.MkdirAll(d.path, os.ModePerm); err != nil {
+	if d.dryRun {
+		d.logger.InfoContext(ctx, "skipping tool install in dry run", "tool", tool)
+		return nil
+	}
+	if err := os.MkdirAll(d.Bin(), os.ModePerm); err != nil {
 		return fmt.Errorf("create dependency dir: %w", err)
 	}
-
-	needsRebuild, err := d.NeedsRebuild(tool, version)
+	unlock, err := d.lock(ctx, tool)
 	if err != nil {
 		return err
 	}
-	if !needsRebuild {
+	defer unlock() //nolint:errcheck
+
+	// Go tools are verified via build info, version markers are no longer needed.
+	if err := os.RemoveAll(d.versionDir(tool)); err != nil {
+		return fmt.Errorf("removing version markers: %w", err)
+	}
+
+	reason := goToolInstallReason(path.Join(d.Bin(), tool), packageURL, version)
+	if len(reason) == 0 {
 		return nil
 	}
 
-	url := packageURL + "@v" + version
-	if err := d.runner.Run(ctx, "go", "install", url); err != nil {
+	url := depURL(packageURL, version)
+	d.logger.InfoContext(ctx, "installing tool", "tool", tool, "version", "v"+version, "reason", reason)
+	env := map[string]string{}
+	if d.offline {
+		if err := d.checkOffline(ctx, tool); err != nil {
+			return err
+		}
+		source, err := d.offlineSource(ctx)
+		if err != nil {
+			return err
+		}
+		env = offlineEnv(source, d.verifyLock)
+	}
+
+	// Install into a temporary GOBIN and move the binary into place,
+	// so concurrent readers never see a partially written binary.
+	tmpBin, err := os.MkdirTemp(d.path, ".install-"+tool+"-*")
+	if err != nil {
+		return fmt.Errorf("create temporary GOBIN: %w", err)
+	}
+	defer os.RemoveAll(tmpBin)
+	env["GOBIN"] = tmpBin
+	if err := d.runner.New(sh.WithEnvironment(env)).Run(ctx, "go", "install", url); err != nil {
+		return fmt.Errorf("install %s: %w", url, err)
+	}
+	installed, err := os.ReadDir(tmpBin)
+	if err != nil {
+		return fmt.Errorf("reading temporary GOBIN: %w", err)
+	}
+	if len(installed) != 1 {
+		return fmt.Errorf("install %s: expected a single binary, got %d", url, len(installed))
+	}
+	if err := os.Rename(path.Join(tmpBin, installed[0].Name()), path.Join(d.Bin(), tool)); err != nil {
 		return fmt.Errorf("install %s: %w", url, err)
 	}
 	return nil
 }
 
-// Checks if a tool in the dependency directory needs to be rebuild.
-func (d *dependencyManager) NeedsRebuild(tool, version string) (needsRebuild bool, err error) {
-	versionFile := path.Join(d.path, "versions", tool, "v"+version)
-	if err := ensureFile(versionFile); err != nil {
-		return false, fmt.Errorf("ensure file: %w", err)
-	}
+// Locks installation of the given tool against concurrent cardboard processes.
+func (d *dependencyManager) lock(ctx context.Context, tool string) (unlock func() error, err error) {
+	return lockFile(ctx, path.Join(d.path, "locks", tool+".lock"))
+}
 
-	// Checks "tool" binary file modification date against version file.
-	// If the version file is newer, tool is of the wrong version.
-	rebuild, err := Path(path.Join(d.Bin(), tool), versionFile)
-	if err != nil {
-		return false, fmt.Errorf("rebuild check: %w", err)
+// Returns why the given go tool binary needs to be (re)installed or an empty string if it is up to date.
+// Module path and version are read from the build info embedded in the binary.
+func goToolInstallReason(bin, packageURL, version string) string {
+	bi, err := buildinfo.ReadFile(bin)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return "not installed"
+	case err != nil:
+		return fmt.Sprintf("reading build info: %v", err)
+	case bi.Path != packageURL:
+		return fmt.Sprintf("installed from %s, want %s", bi.Path, packageURL)
+	case bi.Main.Version != "v"+version:
+		return fmt.Sprintf("installed version %s, want v%s", bi.Main.Version, version)
 	}
-
-	return rebuild, nil
+	return ""
 }
 
-func depURL(packageURL, version string) string {
-	return packageURL + "@v" + version
+// Directory containing version markers of the given tool.
+func (d *dependencyManager) versionDir(tool string) string {
+	return path.Join(d.path, "versions", tool)
 }
 
-// ensure a file and it's file path exist.
-func ensureFile(file string) error {
-	dir := filepath.Dir(file)
-	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
-		return fmt.Errorf("creating directory %s: %w", dir, err)
+// Returns why the given downloaded binary needs to be (re)installed or an empty string if it is up to date.
+// Prebuilt binaries carry no reliable build info, so their version is tracked via marker files.
+func (d *dependencyManager) downloadReason(tool, version string) (string, error) {
+	if _, err := os.Stat(path.Join(d.Bin(), tool)); errors.Is(err, os.ErrNotExist) {
+		return "not installed", nil
+	} else if err != nil {
+		return "", err
 	}
 
-	_, err := os.Stat(file)
-	if os.IsNotExist(err) {
-		f, err := os.Create(file)
-		if err != nil {
-			return fmt.Errorf("creating file %s: %w", file, err)
+	markers, err := os.ReadDir(d.versionDir(tool))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("reading version markers: %w", err)
+	}
+	for _, marker := range markers {
+		if marker.Name() == "v"+version {
+			return "", nil
 		}
-		defer f.Close()
-		return nil
 	}
-	if err != nil {
-		return fmt.Errorf("checking file %s: %w", file, err)
+	if len(markers) == 0 {
+		return "unknown installed version", nil
+	}
+	return fmt.Sprintf("installed version %s, want v%s", markers[0].Name(), version), nil
+}
+
+// Records the installed version of a downloaded binary, replacing stale markers.
+func (d *dependencyManager) markVersion(tool, version string) error {
+	dir := d.versionDir(tool)
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("removing version markers: %w", err)
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("creating directory %s: %w", dir, err)
+	}
+	if err := os.WriteFile(path.Join(dir, "v"+version), nil, 0o644); err != nil {
+		return fmt.Errorf("writing version marker: %w", err)
 	}
 	return nil
 }
+
+func depURL(packageURL, version string) string {
+	return packageURL + "@v" + version
+}
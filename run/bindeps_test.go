@@ -0,0 +1,60 @@
+package run
+
+import (
+	"debug/buildinfo"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_goToolInstallReason(t *testing.T) {
+	t.Parallel()
+
+	self, err := os.Executable()
+	require.NoError(t, err)
+	bi, err := buildinfo.ReadFile(self)
+	require.NoError(t, err)
+
+	assert.Equal(t, "not installed",
+		goToolInstallReason(filepath.Join(t.TempDir(), "missing"), bi.Path, "1.0.0"))
+	assert.Equal(t, "installed from "+bi.Path+", want example.com/tool",
+		goToolInstallReason(self, "example.com/tool", "1.0.0"))
+	assert.Equal(t, "installed version "+bi.Main.Version+", want v1.0.0",
+		goToolInstallReason(self, bi.Path, "1.0.0"))
+
+	notGo := filepath.Join(t.TempDir(), "script")
+	require.NoError(t, os.WriteFile(notGo, []byte("#!/bin/sh\n"), 0o755))
+	assert.Contains(t, goToolInstallReason(notGo, bi.Path, "1.0.0"), "reading build info: ")
+}
+
+func TestDependencyManager_downloadReason(t *testing.T) {
+	t.Parallel()
+
+	dm, err := newDependencyManager(newDependencyRun(), t.TempDir())
+	require.NoError(t, err)
+	reason, err := dm.downloadReason("tool", "1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, "not installed", reason)
+
+	require.NoError(t, os.MkdirAll(dm.Bin(), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dm.Bin(), "tool"), nil, 0o755))
+	reason, err = dm.downloadReason("tool", "1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, "unknown installed version", reason)
+
+	require.NoError(t, dm.markVersion("tool", "0.9.0"))
+	require.NoError(t, dm.markVersion("tool", "1.0.0"))
+	markers, err := os.ReadDir(dm.versionDir("tool"))
+	require.NoError(t, err)
+	require.Len(t, markers, 1, "stale markers are removed")
+
+	reason, err = dm.downloadReason("tool", "1.0.0")
+	require.NoError(t, err)
+	assert.Empty(t, reason)
+	reason, err = dm.downloadReason("tool", "2.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, "installed version v1.0.0, want v2.0.0", reason)
+}
@@ -0,0 +1,231 @@
+package run
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/template"
+)
+
+// Describes a prebuilt binary to download into the project-local dependency directory.
+//
+// URL and Path are Go templates with access to .Version, .OS and .Arch, e.g.:
+// "https://dl.k8s.io/release/v{{.Version}}/bin/{{.OS}}/{{.Arch}}/kubectl".
+type Binary struct {
+	// Name of the binary in the dependency bin directory.
+	Tool string
+	// Version without "v" prefix, same as for RegisterGoTool.
+	Version string
+	// URL template of the binary or the archive containing it.
+	// Archives are detected by their .tar.gz, .tgz or .zip extension.
+	URL string
+	// Path template of the binary within the archive.
+	// Defaults to the first file named like Tool.
+	Path string
+	// Hex encoded SHA256 checksums of the downloaded file keyed by "os/arch", e.g. "linux/amd64".
+	Checksums map[string]string
+}
+
+// Values available in Binary URL and Path templates.
+type binaryTemplateData struct {
+	Version, OS, Arch string
+}
+
+func (b Binary) render(tmpl string) (string, error) {
+	t, err := template.New(b.Tool).Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("parsing template for %s: %w", b.Tool, err)
+	}
+	var out strings.Builder
+	if err := t.Execute(&out, binaryTemplateData{
+		Version: b.Version, OS: runtime.GOOS, Arch: runtime.GOARCH,
+	}); err != nil {
+		return "", fmt.Errorf("rendering template for %s: %w", b.Tool, err)
+	}
+	return out.String(), nil
+}
+
+// Register a prebuilt binary to be downloaded.
+func (d *dependencyManager) RegisterBinary(ctx context.Context, bin Binary) error {
+	if len(bin.Tool) == 0 {
+		return errors.New("binary dependency must have a tool name")
+	}
+	url, err := bin.render(bin.URL)
+	if err != nil {
+		return err
+	}
+	if existing, ok := d.deps[bin.Tool]; ok && existing != url {
+		return fmt.Errorf("conflicting dependency for %s, already have: %s", bin.Tool, existing)
+	}
+
+	installFn := func() error {
+		return d.download(ctx, bin, url)
+	}
+
+	d.deps[bin.Tool] = url
+	d.versions[bin.Tool] = "v" + bin.Version
+	d.downloads[bin.Tool] = bin
+	d.add(bin.Tool, FnWithName("download "+bin.Tool, installFn))
+	return nil
+}
+
+// download a binary dependency into the dependency directory.
+func (d *dependencyManager) download(ctx context.Context, bin Binary, url string) error {
+	if d.dryRun {
+		d.logger.InfoContext(ctx, "skipping tool download in dry run", "tool", bin.Tool)
+		return nil
+	}
+	platform := runtime.GOOS + "/" + runtime.GOARCH
+	checksum, ok := bin.Checksums[platform]
+	if !ok {
+		return fmt.Errorf("no checksum for %s on %s", bin.Tool, platform)
+	}
+
+	if err := os.MkdirAll(d.Bin(), os.ModePerm); err != nil {
+		return fmt.Errorf("create dependency dir: %w", err)
+	}
+	unlock, err := d.lock(ctx, bin.Tool)
+	if err != nil {
+		return err
+	}
+	defer unlock() //nolint:errcheck
+
+	reason, err := d.downloadReason(bin.Tool, bin.Version)
+	if err != nil {
+		return err
+	}
+	if len(reason) == 0 {
+		return nil
+	}
+	if d.offline {
+		return d.checkOffline(ctx, bin.Tool)
+	}
+	d.logger.InfoContext(ctx, "downloading tool", "tool", bin.Tool, "version", "v"+bin.Version, "reason", reason)
+
+	data, err := fetch(ctx, url)
+	if err != nil {
+		return fmt.Errorf("download %s: %w", url, err)
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, checksum) {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", url, checksum, got)
+	}
+
+	binPath := bin.Tool
+	if len(bin.Path) > 0 {
+		if binPath, err = bin.render(bin.Path); err != nil {
+			return err
+		}
+	}
+	binary, err := extractBinary(url, data, binPath, len(bin.Path) > 0)
+	if err != nil {
+		return fmt.Errorf("extract %s from %s: %w", binPath, url, err)
+	}
+	if err := writeFileAtomic(path.Join(d.Bin(), bin.Tool), binary, 0o755); err != nil {
+		return err
+	}
+	return d.markVersion(bin.Tool, bin.Version)
+}
+
+func fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Returns the binary from the downloaded data, extracting it from an archive if needed.
+// When exact is false, the first file with a base name matching binPath is used.
+func extractBinary(url string, data []byte, binPath string, exact bool) ([]byte, error) {
+	matches := func(name string) bool {
+		name = path.Clean(name)
+		if exact {
+			return name == path.Clean(binPath)
+		}
+		return path.Base(name) == binPath
+	}
+
+	switch {
+	case strings.HasSuffix(url, ".tar.gz"), strings.HasSuffix(url, ".tgz"):
+		gr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		tr := tar.NewReader(gr)
+		for {
+			hdr, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				return nil, errors.New("not found in archive")
+			}
+			if err != nil {
+				return nil, err
+			}
+			if hdr.Typeflag == tar.TypeReg && matches(hdr.Name) {
+				return io.ReadAll(tr)
+			}
+		}
+
+	case strings.HasSuffix(url, ".zip"):
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() || !matches(f.Name) {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			return io.ReadAll(rc)
+		}
+		return nil, errors.New("not found in archive")
+
+	default:
+		return data, nil
+	}
+}
+
+// Writes a file by renaming a temporary file into place, so readers never see partial content.
+func writeFileAtomic(file string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".*")
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("writing %s: %w", file, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing %s: %w", file, err)
+	}
+	if err := os.Chmod(f.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), file)
+}
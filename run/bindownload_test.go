@@ -0,0 +1,231 @@
+package run
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pkg.package-operator.run/cardboard/cache"
+	"pkg.package-operator.run/cardboard/sh"
+)
+
+func TestDependencyManager_RegisterBinary(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("#!/bin/sh\necho banana\n")
+	files := map[string][]byte{
+		"/raw/1.0.0/" + runtime.GOOS + "/tool": content,
+		"/archive.tar.gz":                      tarGz(t, "tool-1.0.0/bin/tool", content),
+		"/archive.zip":                         zipped(t, "bin/tool", content),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+	platform := runtime.GOOS + "/" + runtime.GOARCH
+
+	tests := []struct {
+		name     string
+		file     string
+		url      string
+		path     string
+		checksum string
+		err      string
+	}{
+		{
+			name: "raw",
+			file: "/raw/1.0.0/" + runtime.GOOS + "/tool",
+			url:  srv.URL + "/raw/{{.Version}}/{{.OS}}/tool",
+		},
+		{
+			name: "tar.gz",
+			file: "/archive.tar.gz",
+			url:  srv.URL + "/archive.tar.gz",
+			path: "tool-{{.Version}}/bin/tool",
+		},
+		{
+			name: "zip",
+			file: "/archive.zip",
+			url:  srv.URL + "/archive.zip",
+		},
+		{
+			name:     "checksum mismatch",
+			url:      srv.URL + "/archive.zip",
+			checksum: "abc",
+			err:      "checksum mismatch for " + srv.URL + "/archive.zip: expected abc, got ",
+		},
+		{
+			name: "not found",
+			url:  srv.URL + "/missing",
+			err:  "download " + srv.URL + "/missing: unexpected status: 404 Not Found",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			checksum := test.checksum
+			if len(checksum) == 0 {
+				sum := sha256.Sum256(files[test.file])
+				checksum = hex.EncodeToString(sum[:])
+			}
+
+			dm, err := newDependencyManager(newDependencyRun(), t.TempDir())
+			require.NoError(t, err)
+			require.NoError(t, dm.RegisterBinary(t.Context(), Binary{
+				Tool:      "tool",
+				Version:   "1.0.0",
+				URL:       test.url,
+				Path:      test.path,
+				Checksums: map[string]string{platform: checksum},
+			}))
+
+			err = dm.Run(t.Context())
+			if len(test.err) > 0 {
+				require.ErrorContains(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+
+			installed, err := os.ReadFile(filepath.Join(dm.Bin(), "tool"))
+			require.NoError(t, err)
+			assert.Equal(t, content, installed)
+			info, err := os.Stat(filepath.Join(dm.Bin(), "tool"))
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+		})
+	}
+}
+
+func TestDependencyManager_RegisterBinary_conflict(t *testing.T) {
+	t.Parallel()
+
+	dm, err := newDependencyManager(newDependencyRun(), t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, dm.RegisterBinary(t.Context(), Binary{Tool: "tool", Version: "1.0.0", URL: "http://x/{{.Version}}"}))
+	require.NoError(t, dm.RegisterBinary(t.Context(), Binary{Tool: "tool", Version: "1.0.0", URL: "http://x/{{.Version}}"}))
+	require.EqualError(t,
+		dm.RegisterBinary(t.Context(), Binary{Tool: "tool", Version: "2.0.0", URL: "http://x/{{.Version}}"}),
+		"conflicting dependency for tool, already have: http://x/1.0.0")
+}
+
+func tarGz(t *testing.T, name string, content []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	require.NoError(t, tw.WriteHeader(&tar.Header{
+		Name: name, Mode: 0o755, Size: int64(len(content)), Typeflag: tar.TypeReg,
+	}))
+	_, err := tw.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	return buf.Bytes()
+}
+
+func zipped(t *testing.T, name string, content []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create(name)
+	require.NoError(t, err)
+	_, err = w.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+type lazyToolTargets struct{}
+
+func (lazyToolTargets) Use(ctx context.Context, _ []string) error {
+	return sh.New().Run(ctx, "lazytool")
+}
+
+func (lazyToolTargets) Script(ctx context.Context, _ []string) error {
+	return sh.New().Bash(ctx, "lazytool")
+}
+
+func (lazyToolTargets) Other(ctx context.Context, _ []string) error {
+	return sh.New().Bash(ctx, "echo lazy")
+}
+
+func (lazyToolTargets) Skip(context.Context, []string) error { return nil }
+
+func TestManager_Run_lazyToolInstall(t *testing.T) {
+	script := []byte("#!/bin/sh\necho banana\n")
+	sum := sha256.Sum256(script)
+	var downloads atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		downloads.Add(1)
+		_, _ = w.Write(script)
+	}))
+	t.Cleanup(srv.Close)
+
+	t.Setenv("PATH", os.Getenv("PATH"))
+	t.Setenv(cache.DirEnv, t.TempDir())
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/lazy\n"), 0o600))
+	t.Chdir(root)
+	var stdoutBuf, stderrBuf bytes.Buffer
+	newMgr := func(opts ...ManagerOption) *Manager {
+		mgr := New(append([]ManagerOption{WithStdout{&stdoutBuf}, WithStderr{&stderrBuf}, WithLazyToolInstall{}}, opts...)...)
+		require.NoError(t, mgr.RegisterBinary(t.Context(), Binary{
+			Tool: "lazytool", Version: "1.0.0", URL: srv.URL + "/lazytool",
+			Checksums: map[string]string{runtime.GOOS + "/" + runtime.GOARCH: hex.EncodeToString(sum[:])},
+		}))
+		require.NoError(t, mgr.Register(&lazyToolTargets{}))
+		return mgr
+	}
+
+	os.Args = []string{"", "lazyToolTargets:Skip"}
+	require.NoError(t, newMgr().Run(t.Context()))
+	assert.Equal(t, int32(0), downloads.Load())
+
+	os.Args = []string{"", "lazyToolTargets:Other"}
+	require.NoError(t, newMgr().Run(t.Context()))
+	assert.Equal(t, int32(0), downloads.Load(), "scripts only install tools they mention")
+
+	os.Args = []string{"", "lazyToolTargets:Script"}
+	require.NoError(t, os.WriteFile(filepath.Join(root, LockfileName), []byte(lockfileHeader), 0o600))
+	err := newMgr(WithLockfile(LockfileModeVerify)).Run(t.Context())
+	require.ErrorContains(t, err, "lazytool: missing from lockfile")
+	assert.Equal(t, int32(1), downloads.Load())
+
+	os.Args = []string{"", "tools", "lock"}
+	require.NoError(t, newMgr().Run(t.Context()))
+	os.Args = []string{"", "lazyToolTargets:Script"}
+	require.NoError(t, newMgr(WithLockfile(LockfileModeVerify)).Run(t.Context()))
+	assert.Equal(t, int32(1), downloads.Load())
+
+	os.Args = []string{"", "lazyToolTargets:Use"}
+	require.NoError(t, newMgr().Run(t.Context()))
+	assert.Equal(t, int32(1), downloads.Load())
+
+	mgr := newMgr()
+	os.Args = []string{"", "tools"}
+	stdoutBuf.Reset()
+	require.NoError(t, mgr.Run(t.Context()))
+	assert.Contains(t, stdoutBuf.String(), "lazytool  v1.0.0   installed  "+mgr.dm.Bin()+"/lazytool")
+}
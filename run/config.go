@@ -0,0 +1,399 @@
+package run
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+
+	"pkg.package-operator.run/cardboard/cache"
+)
+
+// Name of the config file looked up in the project root.
+const ConfigFileName = "cardboard.yaml"
+
+// ReportFormat controls how the report is printed after a target ran.
+type ReportFormat string
+
+const (
+	// Human readable tree.
+	ReportFormatText ReportFormat = "text"
+	// Machine readable JSON document.
+	ReportFormatJSON ReportFormat = "json"
+	// No report.
+	ReportFormatNone ReportFormat = "none"
+)
+
+// Config contains Manager settings.
+// Settings are resolved in the following order, later sources take precedence:
+// defaults, ManagerOptions, the cardboard.yaml config file, CARDBOARD_* environment variables.
+type Config struct {
+	// Maximum number of dependencies a single ParallelDeps call executes concurrently.
+	// 0 means unlimited.
+	Parallelism int
+	// Format of the report printed after a target ran.
+	ReportFormat ReportFormat
+	// Level of the logger, slog.Default() is used unless set.
+	// Ignored when a custom logger is provided.
+	LogLevel slog.Level
+	// Disable colored output.
+	NoColor bool
+	// Write command output without prefixing lines with the command.
+	NoLogPrefix bool
+	// Directory to store caches and project-local tools in.
+	// Relative paths are resolved against the project root.
+	CacheDir string
+	// Container runtime to use, auto-detected if empty.
+	ContainerRuntime string
+	// How output of parallel dependencies is written.
+	OutputMode OutputMode
+	// Markers to wrap grouped output in.
+	GroupMarkers GroupMarkers
+	// Whether installed tools are verified against the lockfile.
+	Lockfile LockfileMode
+	// Install tools without network access, from ToolsVendorDir or the module cache.
+	Offline bool
+	// GOPROXY-layout directory to install go tools from in offline mode.
+	// Relative paths are resolved against the project root.
+	ToolsVendorDir string
+	// File to record commands executed via sh.Runner to, as JSON lines.
+	// Relative paths are resolved against the project root.
+	Trace string
+	// Record commands executed via sh.Runner without executing them.
+	DryRun bool
+}
+
+func defaultConfig() Config {
+	return Config{
+		ReportFormat: ReportFormatText,
+		LogLevel:     slog.LevelInfo,
+		CacheDir:     cache.DefaultDir,
+		OutputMode:   OutputModeStream,
+		GroupMarkers: GroupMarkersAuto,
+		Lockfile:     LockfileModeOff,
+	}
+}
+
+// A single configuration setting.
+type configSetting struct {
+	// key in the config file.
+	key string
+	// environment variable name.
+	env string
+	get func(c *Config) string
+	set func(c *Config, v string) error
+}
+
+var configSettings = []configSetting{
+	{
+		key: "parallelism", env: "CARDBOARD_PARALLELISM",
+		get: func(c *Config) string { return strconv.Itoa(c.Parallelism) },
+		set: func(c *Config, v string) error {
+			p, err := strconv.Atoi(v)
+			if err != nil || p < 0 {
+				return fmt.Errorf("must be a non-negative integer, got %q", v)
+			}
+			c.Parallelism = p
+			return nil
+		},
+	},
+	{
+		key: "reportFormat", env: "CARDBOARD_REPORT_FORMAT",
+		get: func(c *Config) string { return string(c.ReportFormat) },
+		set: func(c *Config, v string) error {
+			return setEnum(&c.ReportFormat, v, ReportFormatText, ReportFormatJSON, ReportFormatNone)
+		},
+	},
+	{
+		key: "logLevel", env: "CARDBOARD_LOG_LEVEL",
+		get: func(c *Config) string { return c.LogLevel.String() },
+		set: func(c *Config, v string) error { return c.LogLevel.UnmarshalText([]byte(v)) },
+	},
+	{
+		// Any non-empty value disables colors, see https://no-color.org.
+		key: "noColor", env: "NO_COLOR",
+		get: func(c *Config) string { return strconv.FormatBool(c.NoColor) },
+		set: func(c *Config, v string) error {
+			b, err := strconv.ParseBool(v)
+			c.NoColor = b || (err != nil && len(v) > 0)
+			return nil
+		},
+	},
+	{
+		key: "noLogPrefix", env: "CARDBOARD_NO_LOG_PREFIX",
+		get: func(c *Config) string { return strconv.FormatBool(c.NoLogPrefix) },
+		set: func(c *Config, v string) error {
+			b, err := strconv.ParseBool(v)
+			c.NoLogPrefix = b || (err != nil && len(v) > 0)
+			return nil
+		},
+	},
+	{
+		key: "cacheDir", env: cache.DirEnv,
+		get: func(c *Config) string { return c.CacheDir },
+		set: func(c *Config, v string) error {
+			if len(v) == 0 {
+				return errors.New("must not be empty")
+			}
+			c.CacheDir = v
+			return nil
+		},
+	},
+	{
+		key: "containerRuntime", env: "CARDBOARD_CONTAINER_RUNTIME",
+		get: func(c *Config) string { return c.ContainerRuntime },
+		set: func(c *Config, v string) error { return setEnum(&c.ContainerRuntime, v, "", "podman", "docker") },
+	},
+	{
+		key: "outputMode", env: "CARDBOARD_OUTPUT_MODE",
+		get: func(c *Config) string { return string(c.OutputMode) },
+		set: func(c *Config, v string) error {
+			return setEnum(&c.OutputMode, v, OutputModeStream, OutputModeGrouped, OutputModeGroupedOnFailure)
+		},
+	},
+	{
+		key: "groupMarkers", env: "CARDBOARD_GROUP_MARKERS",
+		get: func(c *Config) string { return string(c.GroupMarkers) },
+		set: func(c *Config, v string) error {
+			return setEnum(&c.GroupMarkers, v,
+				GroupMarkersAuto, GroupMarkersNone, GroupMarkersGitHub, GroupMarkersGitLab)
+		},
+	},
+	{
+		key: "lockfile", env: "CARDBOARD_LOCKFILE",
+		get: func(c *Config) string { return string(c.Lockfile) },
+		set: func(c *Config, v string) error {
+			return setEnum(&c.Lockfile, v, LockfileModeOff, LockfileModeVerify)
+		},
+	},
+	{
+		key: "offline", env: "CARDBOARD_OFFLINE",
+		get: func(c *Config) string { return strconv.FormatBool(c.Offline) },
+		set: func(c *Config, v string) error {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("must be a boolean, got %q", v)
+			}
+			c.Offline = b
+			return nil
+		},
+	},
+	{
+		key: "toolsVendorDir", env: "CARDBOARD_TOOLS_VENDOR_DIR",
+		get: func(c *Config) string { return c.ToolsVendorDir },
+		set: func(c *Config, v string) error {
+			c.ToolsVendorDir = v
+			return nil
+		},
+	},
+	{
+		key: "trace", env: "CARDBOARD_TRACE",
+		get: func(c *Config) string { return c.Trace },
+		set: func(c *Config, v string) error {
+			c.Trace = v
+			return nil
+		},
+	},
+	{
+		key: "dryRun", env: "CARDBOARD_DRY_RUN",
+		get: func(c *Config) string { return strconv.FormatBool(c.DryRun) },
+		set: func(c *Config, v string) error {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("must be a boolean, got %q", v)
+			}
+			c.DryRun = b
+			return nil
+		},
+	},
+}
+
+func setEnum[T ~string](dst *T, v string, allowed ...T) error {
+	if !slices.Contains(allowed, T(v)) {
+		allowedStrings := make([]string, len(allowed))
+		for i, a := range allowed {
+			allowedStrings[i] = fmt.Sprintf("%q", a)
+		}
+		return fmt.Errorf("must be one of %s, got %q", strings.Join(allowedStrings, ", "), v)
+	}
+	*dst = T(v)
+	return nil
+}
+
+// Effective configuration and where each setting came from.
+type resolvedConfig struct {
+	Config
+	// setting key -> source description.
+	sources map[string]string
+}
+
+// Resolves the effective configuration from
+// options (already applied to base), the given config file and environment.
+func resolveConfig(
+	base Config, configFile string, lookupEnv func(string) (string, bool),
+) (*resolvedConfig, error) {
+	rc := &resolvedConfig{Config: base, sources: map[string]string{}}
+	defaults := defaultConfig()
+	for _, s := range configSettings {
+		if s.get(&rc.Config) != s.get(&defaults) {
+			rc.sources[s.key] = "option"
+		} else {
+			rc.sources[s.key] = "default"
+		}
+	}
+
+	if len(configFile) > 0 {
+		fileValues, err := readConfigFile(configFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range configSettings {
+			v, ok := fileValues[s.key]
+			if !ok {
+				continue
+			}
+			if err := s.set(&rc.Config, v); err != nil {
+				return nil, fmt.Errorf("%s: invalid value for %s: %w", configFile, s.key, err)
+			}
+			rc.sources[s.key] = "file " + configFile
+			delete(fileValues, s.key)
+		}
+		for k := range fileValues {
+			return nil, fmt.Errorf("%s: unknown setting %q", configFile, k)
+		}
+	}
+
+	for _, s := range configSettings {
+		v, ok := lookupEnv(s.env)
+		if !ok {
+			continue
+		}
+		if err := s.set(&rc.Config, v); err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", s.env, err)
+		}
+		rc.sources[s.key] = "env " + s.env
+	}
+	return rc, nil
+}
+
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+	values := map[string]string{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// Looks for the config file in the current directory and its parents,
+// stopping at the project root.
+// Returns an empty string if no config file was found.
+func findConfigFile() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	root, err := cache.ProjectRoot()
+	if err != nil {
+		return "", err
+	}
+	for {
+		file := filepath.Join(dir, ConfigFileName)
+		if _, err := os.Stat(file); err == nil {
+			return file, nil
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if dir == root || parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// Whether the setting with the given key was configured via option, config file or environment.
+func (rc *resolvedConfig) isSet(key string) bool {
+	source, ok := rc.sources[key]
+	return ok && source != "default"
+}
+
+// Prints the effective configuration.
+func (rc *resolvedConfig) print(out io.Writer) error {
+	fmt.Fprintln(out, "Effective configuration:")
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, s := range configSettings {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", s.key, s.get(&rc.Config), rc.sources[s.key])
+	}
+	return w.Flush()
+}
+
+// Maximum number of dependencies executed concurrently by a single ParallelDeps call. 0 means unlimited.
+type WithParallelism int
+
+func (p WithParallelism) ApplyToManager(m *Manager) {
+	m.config.Parallelism = int(p)
+}
+
+// Format of the report printed after a target ran.
+type WithReportFormat ReportFormat
+
+func (f WithReportFormat) ApplyToManager(m *Manager) {
+	m.config.ReportFormat = ReportFormat(f)
+}
+
+// Level of the logger, ignored when a custom logger is provided via WithLogger.
+type WithLogLevel slog.Level
+
+func (l WithLogLevel) ApplyToManager(m *Manager) {
+	m.config.LogLevel = slog.Level(l)
+}
+
+// Directory to store caches and project-local tools in.
+// Relative paths are resolved against the project root.
+type WithCacheDir string
+
+func (d WithCacheDir) ApplyToManager(m *Manager) {
+	m.config.CacheDir = string(d)
+}
+
+// Container runtime to use instead of auto-detecting it.
+type WithContainerRuntime string
+
+func (cr WithContainerRuntime) ApplyToManager(m *Manager) {
+	m.config.ContainerRuntime = string(cr)
+}
+
+// Whether installed tools are verified against the lockfile.
+type WithLockfile LockfileMode
+
+func (l WithLockfile) ApplyToManager(m *Manager) {
+	m.config.Lockfile = LockfileMode(l)
+}
+
+// Install tools without network access.
+// Go tools are installed from the given GOPROXY-layout directory or the module cache if empty.
+type WithOfflineTools string
+
+func (vendorDir WithOfflineTools) ApplyToManager(m *Manager) {
+	m.config.Offline = true
+	m.config.ToolsVendorDir = string(vendorDir)
+}
+
+// Path of the config file to load instead of looking up cardboard.yaml.
+type WithConfigFile string
+
+func (f WithConfigFile) ApplyToManager(m *Manager) {
+	m.configFile = string(f)
+}
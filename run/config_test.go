@@ -0,0 +1,182 @@
+package run
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pkg.package-operator.run/cardboard/cache"
+)
+
+func Test_resolveConfig(t *testing.T) {
+	t.Parallel()
+
+	configFile := filepath.Join(t.TempDir(), ConfigFileName)
+	require.NoError(t, os.WriteFile(configFile, []byte(`
+parallelism: 4
+reportFormat: json
+cacheDir: /tmp/cache
+`), 0o644))
+
+	base := defaultConfig()
+	base.Parallelism = 2
+	base.LogLevel = slog.LevelDebug
+	env := map[string]string{
+		"CARDBOARD_PARALLELISM": "8",
+		"CARDBOARD_OUTPUT_MODE": "grouped",
+	}
+	lookupEnv := func(k string) (string, bool) {
+		v, ok := env[k]
+		return v, ok
+	}
+
+	rc, err := resolveConfig(base, configFile, lookupEnv)
+	require.NoError(t, err)
+
+	assert.Equal(t, Config{
+		Parallelism:  8,
+		ReportFormat: ReportFormatJSON,
+		LogLevel:     slog.LevelDebug,
+		CacheDir:     "/tmp/cache",
+		OutputMode:   OutputModeGrouped,
+		GroupMarkers: GroupMarkersAuto,
+		Lockfile:     LockfileModeOff,
+	}, rc.Config)
+
+	var out bytes.Buffer
+	require.NoError(t, rc.print(&out))
+	assert.Equal(t, `Effective configuration:
+parallelism       8           env CARDBOARD_PARALLELISM
+reportFormat      json        file `+configFile+`
+logLevel          DEBUG       option
+noColor           false       default
+noLogPrefix       false       default
+cacheDir          /tmp/cache  file `+configFile+`
+containerRuntime              default
+outputMode        grouped     env CARDBOARD_OUTPUT_MODE
+groupMarkers      auto        default
+lockfile          off         default
+offline           false       default
+toolsVendorDir                default
+trace                         default
+dryRun            false       default
+`, out.String())
+}
+
+func Test_resolveConfig_errors(t *testing.T) {
+	t.Parallel()
+
+	noEnv := func(string) (string, bool) { return "", false }
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		err  string
+	}{
+		{
+			name: "unknown setting",
+			file: "banana: true\n",
+			err:  `unknown setting "banana"`,
+		},
+		{
+			name: "invalid file value",
+			file: "reportFormat: xml\n",
+			err:  `invalid value for reportFormat: must be one of "text", "json", "none", got "xml"`,
+		},
+		{
+			name: "invalid env value",
+			env:  map[string]string{"CARDBOARD_PARALLELISM": "-1"},
+			err:  `invalid value for CARDBOARD_PARALLELISM: must be a non-negative integer, got "-1"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var configFile string
+			if len(test.file) > 0 {
+				configFile = filepath.Join(t.TempDir(), ConfigFileName)
+				require.NoError(t, os.WriteFile(configFile, []byte(test.file), 0o644))
+			}
+			lookupEnv := noEnv
+			if test.env != nil {
+				lookupEnv = func(k string) (string, bool) {
+					v, ok := test.env[k]
+					return v, ok
+				}
+			}
+
+			_, err := resolveConfig(defaultConfig(), configFile, lookupEnv)
+			require.ErrorContains(t, err, test.err)
+		})
+	}
+}
+
+func TestManager_Run_config(t *testing.T) {
+	// Other tests running the Manager export the cache directory.
+	t.Setenv(cache.DirEnv, "")
+	require.NoError(t, os.Unsetenv(cache.DirEnv))
+
+	var (
+		stdoutBuf bytes.Buffer
+		stderrBuf bytes.Buffer
+	)
+	mgr := New(WithStderr{&stderrBuf}, WithStdout{&stdoutBuf}, WithParallelism(3))
+
+	os.Args = []string{"", "config"}
+	require.NoError(t, mgr.Run(t.Context()))
+	assert.Contains(t, stdoutBuf.String(), "parallelism       3       option\n")
+	assert.Empty(t, stderrBuf.String())
+}
+
+func TestManager_Run_reportJSON(t *testing.T) {
+	var (
+		stdoutBuf bytes.Buffer
+		stderrBuf bytes.Buffer
+	)
+	mgr := New(WithStderr{&stderrBuf}, WithStdout{&stdoutBuf}, WithReportFormat(ReportFormatJSON))
+	require.NoError(t, mgr.Register(&MyThing{field: "hans", mgr: mgr}))
+
+	os.Args = []string{"", "MyThing:TestWithDepErr"}
+	require.Error(t, mgr.Run(t.Context()))
+	assert.Contains(t, stderrBuf.String(), `"name": "run.MyThing{field:hans}.privateErr(\"banana\")",`)
+	assert.Contains(t, stderrBuf.String(), `"error": "explosion"`)
+}
+
+func TestNew_loggerAndColors(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	t.Setenv("CARDBOARD_NO_LOG_PREFIX", "true")
+
+	m := New()
+	assert.Same(t, slog.Default(), m.logger, "default logger unless a log level is configured")
+	assert.True(t, m.dr.noColor)
+	assert.True(t, m.rc.NoLogPrefix)
+
+	m = New(WithLogLevel(slog.LevelDebug))
+	assert.NotSame(t, slog.Default(), m.logger)
+	assert.True(t, m.logger.Enabled(t.Context(), slog.LevelDebug))
+}
+
+func TestNew_unresolvableCacheDir(t *testing.T) {
+	// Other tests running the Manager export the cache directory.
+	t.Setenv(cache.DirEnv, "")
+	require.NoError(t, os.Unsetenv(cache.DirEnv))
+	// Relative cache directories cannot be resolved without a working directory.
+	wd := t.TempDir()
+	t.Chdir(wd)
+	require.NoError(t, os.Remove(wd))
+	configFile := filepath.Join(t.TempDir(), ConfigFileName)
+	require.NoError(t, os.WriteFile(configFile, nil, 0o644))
+
+	var stderrBuf bytes.Buffer
+	mgr := New(WithStderr{&stderrBuf}, WithConfigFile(configFile), WithCacheDir(".cache"))
+	require.NoError(t, mgr.Register(&MyThing{mgr: mgr}))
+
+	os.Args = []string{"", "MyThing:Test"}
+	require.ErrorContains(t, mgr.Run(t.Context()), "resolving cache directory")
+}
@@ -3,11 +3,14 @@ package run
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"reflect"
 	"runtime"
 	"runtime/debug"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -40,6 +43,7 @@ func newDependencyRun() *dependencyRun {
 	return &dependencyRun{
 		ran:    map[string]*depOnce{},
 		childs: map[string][]string{},
+		waits:  map[string]map[string]int{},
 	}
 }
 
@@ -48,7 +52,14 @@ type dependencyRun struct {
 	ran    map[string]*depOnce
 	root   string
 	childs map[string][]string
+	// dependencies currently waited on, keyed by the waiting dependency.
+	waits  map[string]map[string]int
 	mux    sync.Mutex
+	output *outputConfig
+	// maximum number of dependencies a single Parallel call executes concurrently, 0 is unlimited.
+	parallelism int
+	// print reports without colors.
+	noColor bool
 }
 
 func (r *dependencyRun) Report() string {
@@ -66,6 +77,72 @@ func (r *dependencyRun) Report() string {
 	return report.String()
 }
 
+// Returns a single line per target with the number of dependencies it ran.
+func (r *dependencyRun) compactReport() string {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	var report bytes.Buffer
+	for _, node := range r.reportNodes(r.childs[r.root]) {
+		deps, failed := countNodes(node.Children)
+		status := r.colorize("[OK]", greenColor)
+		if len(node.Error) > 0 {
+			status = r.colorize("[ERR]", redColor)
+		}
+		fmt.Fprintf(&report, "%s %s [took %s] %d deps, %d failed\n", status, node.Name, node.Took, deps, failed)
+	}
+	return report.String()
+}
+
+func countNodes(nodes []reportNode) (total, failed int) {
+	for _, n := range nodes {
+		t, f := countNodes(n.Children)
+		total += t + 1
+		failed += f
+		if len(n.Error) > 0 {
+			failed++
+		}
+	}
+	return total, failed
+}
+
+type reportNode struct {
+	ID       string        `json:"id"`
+	Name     string        `json:"name"`
+	Tags     []string      `json:"tags,omitempty"`
+	Status   string        `json:"status"`
+	Took     time.Duration `json:"tookNanoseconds"`
+	Error    string        `json:"error,omitempty"`
+	Children []reportNode  `json:"children,omitempty"`
+}
+
+// Returns the report as JSON document.
+func (r *dependencyRun) ReportJSON() ([]byte, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return json.MarshalIndent(r.reportNodes(r.childs[r.root]), "", "  ")
+}
+
+func (r *dependencyRun) reportNodes(childs []string) []reportNode {
+	nodes := make([]reportNode, 0, len(childs))
+	for _, child := range childs {
+		entry := r.ran[child]
+		node := reportNode{
+			ID:       child,
+			Name:     entry.DisplayName(),
+			Tags:     entry.Tags(),
+			Status:   "ok",
+			Took:     entry.took,
+			Children: r.reportNodes(r.childs[child]),
+		}
+		if entry.err != nil {
+			node.Status = "error"
+			node.Error = entry.err.Error()
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
 func (r *dependencyRun) traverseTree(t treeprint.Tree, childs []string) {
 	for _, child := range childs {
 		txt := r.printNode(child)
@@ -80,8 +157,8 @@ const (
 	resetColor  = "\033[0m"
 )
 
-func colorize(t, color string) string {
-	if NoColor {
+func (r *dependencyRun) colorize(t, color string) string {
+	if r.noColor {
 		return t
 	}
 	return color + t + resetColor
@@ -91,14 +168,17 @@ func (r *dependencyRun) printNode(child string) string {
 	entry := r.ran[child]
 	var txt string
 	if entry.err == nil {
-		txt += colorize("[OK] ", greenColor)
+		txt += r.colorize("[OK] ", greenColor)
 	} else {
-		txt += colorize("[ERR] ", redColor)
+		txt += r.colorize("[ERR] ", redColor)
+	}
+	txt += entry.DisplayName()
+	if tags := entry.Tags(); len(tags) > 0 {
+		txt += fmt.Sprintf(" [%s]", strings.Join(tags, ", "))
 	}
-	txt += child
-	txt += colorize(fmt.Sprintf(" [took %s]", entry.took), yellowColor)
+	txt += r.colorize(fmt.Sprintf(" [took %s]", entry.took), yellowColor)
 	if entry.err != nil && !r.childHasError(r.childs[child]) {
-		txt += "\n" + colorize(entry.err.Error(), redColor)
+		txt += "\n" + r.colorize(entry.err.Error(), redColor)
 	}
 	return txt
 }
@@ -121,15 +201,34 @@ func (r *dependencyRun) Parallel(ctx context.Context, parent DependencyIDer, dep
 		errsMux sync.Mutex
 	)
 
-	wg.Add(len(deps))
-	localDeps := make([]Dependency, len(deps))
-	for i, dep := range deps {
-		localDeps[i] = r.get(dep, parent.ID())
+	caller := callerID(ctx, parent)
+	localDeps := make([]Dependency, 0, len(deps))
+	defer func() { r.release(caller, localDeps) }()
+	for _, dep := range deps {
+		localDep, err := r.get(ctx, dep, caller)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("running %s: %w", dep.ID(), err))
+			continue
+		}
+		localDeps = append(localDeps, localDep)
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	var sem chan struct{}
+	if r.parallelism > 0 {
+		sem = make(chan struct{}, r.parallelism)
 	}
+	wg.Add(len(localDeps))
 	for _, dep := range localDeps {
 		go func() {
 			defer wg.Done()
-			if err := dep.Run(ctx); err != nil {
+			if sem != nil {
+				sem <- struct{}{}
+				defer func() { <-sem }()
+			}
+			if err := r.output.run(ctx, dep); err != nil {
 				errsMux.Lock()
 				errs = append(errs, fmt.Errorf("running %s: %w", dep.ID(), err))
 				errsMux.Unlock()
@@ -142,9 +241,15 @@ func (r *dependencyRun) Parallel(ctx context.Context, parent DependencyIDer, dep
 
 // Executes dependencies one after the other.
 func (r *dependencyRun) Serial(ctx context.Context, parent DependencyIDer, deps ...Dependency) error {
-	localDeps := make([]Dependency, len(deps))
-	for i, dep := range deps {
-		localDeps[i] = r.get(dep, parent.ID())
+	caller := callerID(ctx, parent)
+	localDeps := make([]Dependency, 0, len(deps))
+	defer func() { r.release(caller, localDeps) }()
+	for _, dep := range deps {
+		localDep, err := r.get(ctx, dep, caller)
+		if err != nil {
+			return fmt.Errorf("running %s: %w", dep.ID(), err)
+		}
+		localDeps = append(localDeps, localDep)
 	}
 	for _, dep := range localDeps {
 		if err := dep.Run(ctx); err != nil {
@@ -154,33 +259,109 @@ func (r *dependencyRun) Serial(ctx context.Context, parent DependencyIDer, deps
 	return nil
 }
 
-func (r *dependencyRun) get(dep Dependency, parent string) Dependency {
+// Returns the memoized dependency and registers parent as waiting on it.
+// Fails if waiting on the dependency would close a dependency cycle.
+func (r *dependencyRun) get(ctx context.Context, dep Dependency, parent string) (Dependency, error) {
 	r.mux.Lock()
 	defer r.mux.Unlock()
 	if r.root == "" {
 		r.root = parent
 	}
 
-	r.childs[parent] = append(r.childs[parent], dep.ID())
-	out, ok := r.ran[dep.ID()]
+	id := dep.ID()
+	if cycle := r.cycle(ctx, parent, id); cycle != nil {
+		return nil, &DependencyCycleError{Path: cycle}
+	}
+
+	if r.waits[parent] == nil {
+		r.waits[parent] = map[string]int{}
+	}
+	r.waits[parent][id]++
+
+	r.childs[parent] = append(r.childs[parent], id)
+	out, ok := r.ran[id]
 	if !ok {
 		out = newOnce(dep)
-		r.ran[dep.ID()] = out
+		r.ran[id] = out
 	}
-	return out
+	return out, nil
 }
 
-type dep struct {
-	id  string
-	run func(ctx context.Context) error
+// Removes wait registrations of parent created by get.
+func (r *dependencyRun) release(parent string, deps []Dependency) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	for _, dep := range deps {
+		r.waits[parent][dep.ID()]--
+		if r.waits[parent][dep.ID()] <= 0 {
+			delete(r.waits[parent], dep.ID())
+		}
+	}
+	if len(r.waits[parent]) == 0 {
+		delete(r.waits, parent)
+	}
 }
 
-func (d *dep) ID() string {
-	return d.id
+// Returns the dependency cycle that parent waiting on id would create or nil.
+// Must be called with r.mux held.
+func (r *dependencyRun) cycle(ctx context.Context, parent, id string) []string {
+	// Cycles within the same call chain.
+	stack := callStack(ctx)
+	if i := slices.Index(stack, id); i >= 0 {
+		return append(slices.Clone(stack[i:]), id)
+	}
+	if parent == id {
+		return []string{parent, id}
+	}
+
+	// Cycles across call chains running in parallel,
+	// e.g. id is already waiting on parent in another goroutine.
+	if path := r.waitPath(id, parent, map[string]struct{}{}); path != nil {
+		return append([]string{parent}, path...)
+	}
+	return nil
 }
 
-func (d *dep) Run(ctx context.Context) error {
-	return d.run(ctx)
+// Returns the chain of waiting dependencies leading from -> to or nil.
+func (r *dependencyRun) waitPath(from, to string, seen map[string]struct{}) []string {
+	if from == to {
+		return []string{to}
+	}
+	if _, ok := seen[from]; ok {
+		return nil
+	}
+	seen[from] = struct{}{}
+
+	next := slices.Sorted(maps.Keys(r.waits[from]))
+	for _, n := range next {
+		if path := r.waitPath(n, to, seen); path != nil {
+			return append([]string{from}, path...)
+		}
+	}
+	return nil
+}
+
+type callStackContextKey struct{}
+
+// Returns the IDs of dependencies executing in the call chain of ctx.
+func callStack(ctx context.Context) []string {
+	stack, _ := ctx.Value(callStackContextKey{}).([]string)
+	return stack
+}
+
+// Returns a new context with id pushed onto the call stack.
+func contextWithCall(ctx context.Context, id string) context.Context {
+	stack := callStack(ctx)
+	return context.WithValue(ctx, callStackContextKey{}, append(slices.Clip(stack), id))
+}
+
+// Returns the ID of the dependency currently executing in ctx,
+// falling back to the given parent.
+func callerID(ctx context.Context, parent DependencyIDer) string {
+	if stack := callStack(ctx); len(stack) > 0 {
+		return stack[len(stack)-1]
+	}
+	return parent.ID()
 }
 
 type fn interface {
@@ -189,32 +370,17 @@ type fn interface {
 
 // Wraps a struct method with no parameters for the dependency handler.
 func Meth[T fn](s any, fn T) Dependency {
-	return FnWithName(methID(s, fn), fn)
+	return Func(fn).Method(s).withRenderedArgs()
 }
 
 // Wraps a function with no parameters for the dependency handler.
 func Fn[T fn](fn T) Dependency {
-	return FnWithName(funcID(fn), fn)
+	return Func(fn).withRenderedArgs()
 }
 
 // Wraps a function with no parameters and a specific name for the dependency handler.
 func FnWithName[T fn](name string, fn T) Dependency {
-	return &dep{
-		id: name,
-		run: func(ctx context.Context) error {
-			switch v := any(fn).(type) {
-			case func():
-				v()
-			case func() error:
-				return v()
-			case func(context.Context):
-				v(ctx)
-			case func(context.Context) error:
-				return v(ctx)
-			}
-			return nil
-		},
-	}
+	return Func(fn).WithID(name)
 }
 
 type fn1[A any] interface {
@@ -223,32 +389,17 @@ type fn1[A any] interface {
 
 // Wraps a struct method with one parameters for the dependency handler.
 func Meth1[T fn1[A], A any](s any, fn T, a1 A) Dependency {
-	return Fn1WithName(methID(s, fn, a1), fn, a1)
+	return Func(fn, a1).Method(s).withRenderedArgs()
 }
 
 // Wraps a function with one parameter for the dependency handler.
 func Fn1[T fn1[A], A any](fn T, a1 A) Dependency {
-	return Fn1WithName(funcID(fn, a1), fn, a1)
+	return Func(fn, a1).withRenderedArgs()
 }
 
 // Wraps a function with one parameter and a specific name for the dependency handler.
 func Fn1WithName[T fn1[A], A any](name string, fn T, a1 A) Dependency {
-	return &dep{
-		id: name,
-		run: func(ctx context.Context) error {
-			switch v := any(fn).(type) {
-			case func(A):
-				v(a1)
-			case func(A) error:
-				return v(a1)
-			case func(context.Context, A):
-				v(ctx, a1)
-			case func(context.Context, A) error:
-				return v(ctx, a1)
-			}
-			return nil
-		},
-	}
+	return Func(fn, a1).WithID(name)
 }
 
 type fn2[A, B any] interface {
@@ -257,32 +408,17 @@ type fn2[A, B any] interface {
 
 // Wraps a struct method with two parameters for the dependency handler.
 func Meth2[T fn2[A, B], A, B any](s any, fn T, a1 A, a2 B) Dependency {
-	return Fn2WithName(methID(s, fn, a1, a2), fn, a1, a2)
+	return Func(fn, a1, a2).Method(s).withRenderedArgs()
 }
 
 // Wraps a function with two parameters for the dependency handler.
 func Fn2[T fn2[A, B], A, B any](fn T, a1 A, a2 B) Dependency {
-	return Fn2WithName(funcID(fn, a1, a2), fn, a1, a2)
+	return Func(fn, a1, a2).withRenderedArgs()
 }
 
 // Wraps a function with two parameters and a specific name for the dependency handler.
 func Fn2WithName[T fn2[A, B], A, B any](name string, fn T, a1 A, a2 B) Dependency {
-	return &dep{
-		id: name,
-		run: func(ctx context.Context) error {
-			switch v := any(fn).(type) {
-			case func(A, B):
-				v(a1, a2)
-			case func(A, B) error:
-				return v(a1, a2)
-			case func(context.Context, A, B):
-				v(ctx, a1, a2)
-			case func(context.Context, A, B) error:
-				return v(ctx, a1, a2)
-			}
-			return nil
-		},
-	}
+	return Func(fn, a1, a2).WithID(name)
 }
 
 type fn3[A, B, C any] interface {
@@ -291,32 +427,17 @@ type fn3[A, B, C any] interface {
 
 // Wraps a struct method with three parameters for the dependency handler.
 func Meth3[T fn3[A, B, C], A, B, C any](s any, fn T, a1 A, a2 B, a3 C) Dependency {
-	return Fn3WithName(methID(s, fn, a1, a2, a3), fn, a1, a2, a3)
+	return Func(fn, a1, a2, a3).Method(s).withRenderedArgs()
 }
 
 // Wraps a function with three parameters for the dependency handler.
 func Fn3[T fn3[A, B, C], A, B, C any](fn T, a1 A, a2 B, a3 C) Dependency {
-	return Fn3WithName(funcID(fn, a1, a2, a3), fn, a1, a2, a3)
+	return Func(fn, a1, a2, a3).withRenderedArgs()
 }
 
 // Wraps a function with three parameters and a specific name for the dependency handler.
 func Fn3WithName[T fn3[A, B, C], A, B, C any](name string, fn T, a1 A, a2 B, a3 C) Dependency {
-	return &dep{
-		id: name,
-		run: func(ctx context.Context) error {
-			switch v := any(fn).(type) {
-			case func(A, B, C):
-				v(a1, a2, a3)
-			case func(A, B, C) error:
-				return v(a1, a2, a3)
-			case func(context.Context, A, B, C):
-				v(ctx, a1, a2, a3)
-			case func(context.Context, A, B, C) error:
-				return v(ctx, a1, a2, a3)
-			}
-			return nil
-		},
-	}
+	return Func(fn, a1, a2, a3).WithID(name)
 }
 
 type fn4[A, B, C, D any] interface {
@@ -325,32 +446,17 @@ type fn4[A, B, C, D any] interface {
 
 // Wraps a struct method with four parameters for the dependency handler.
 func Meth4[T fn4[A, B, C, D], A, B, C, D any](s any, fn T, a1 A, a2 B, a3 C, a4 D) Dependency {
-	return Fn4WithName(methID(s, fn, a1, a2, a3, a4), fn, a1, a2, a3, a4)
+	return Func(fn, a1, a2, a3, a4).Method(s).withRenderedArgs()
 }
 
 // Wraps a function with four parameters for the dependency handler.
 func Fn4[T fn4[A, B, C, D], A, B, C, D any](fn T, a1 A, a2 B, a3 C, a4 D) Dependency {
-	return Fn4WithName(funcID(fn, a1, a2, a3, a4), fn, a1, a2, a3, a4)
+	return Func(fn, a1, a2, a3, a4).withRenderedArgs()
 }
 
 // Wraps a function with four parameters and a specific name for the dependency handler.
 func Fn4WithName[T fn4[A, B, C, D], A, B, C, D any](name string, fn T, a1 A, a2 B, a3 C, a4 D) Dependency {
-	return &dep{
-		id: name,
-		run: func(ctx context.Context) error {
-			switch v := any(fn).(type) {
-			case func(A, B, C, D):
-				v(a1, a2, a3, a4)
-			case func(A, B, C, D) error:
-				return v(a1, a2, a3, a4)
-			case func(context.Context, A, B, C, D):
-				v(ctx, a1, a2, a3, a4)
-			case func(context.Context, A, B, C, D) error:
-				return v(ctx, a1, a2, a3, a4)
-			}
-			return nil
-		},
-	}
+	return Func(fn, a1, a2, a3, a4).WithID(name)
 }
 
 type fn5[A, B, C, D, E any] interface {
@@ -360,32 +466,17 @@ type fn5[A, B, C, D, E any] interface {
 
 // Wraps a struct method with five parameters for the dependency handler.
 func Meth5[T fn5[A, B, C, D, E], A, B, C, D, E any](s any, fn T, a1 A, a2 B, a3 C, a4 D, a5 E) Dependency {
-	return Fn5WithName(methID(s, fn, a1, a2, a3, a4, a5), fn, a1, a2, a3, a4, a5)
+	return Func(fn, a1, a2, a3, a4, a5).Method(s).withRenderedArgs()
 }
 
 // Wraps a function with five parameters for the dependency handler.
 func Fn5[T fn5[A, B, C, D, E], A, B, C, D, E any](fn T, a1 A, a2 B, a3 C, a4 D, a5 E) Dependency {
-	return Fn5WithName(funcID(fn, a1, a2, a3, a4, a5), fn, a1, a2, a3, a4, a5)
+	return Func(fn, a1, a2, a3, a4, a5).withRenderedArgs()
 }
 
 // Wraps a function with five parameters and a specific name for the dependency handler.
 func Fn5WithName[T fn5[A, B, C, D, E], A, B, C, D, E any](name string, fn T, a1 A, a2 B, a3 C, a4 D, a5 E) Dependency {
-	return &dep{
-		id: name,
-		run: func(ctx context.Context) error {
-			switch v := any(fn).(type) {
-			case func(A, B, C, D, E):
-				v(a1, a2, a3, a4, a5)
-			case func(A, B, C, D, E) error:
-				return v(a1, a2, a3, a4, a5)
-			case func(context.Context, A, B, C, D, E):
-				v(ctx, a1, a2, a3, a4, a5)
-			case func(context.Context, A, B, C, D, E) error:
-				return v(ctx, a1, a2, a3, a4, a5)
-			}
-			return nil
-		},
-	}
+	return Func(fn, a1, a2, a3, a4, a5).WithID(name)
 }
 
 type fn6[A, B, C, D, E, F any] interface {
@@ -395,46 +486,37 @@ type fn6[A, B, C, D, E, F any] interface {
 
 // Wraps a struct method with six parameters for the dependency handler.
 func Meth6[T fn6[A, B, C, D, E, F], A, B, C, D, E, F any](s any, fn T, a1 A, a2 B, a3 C, a4 D, a5 E, a6 F) Dependency {
-	return Fn6WithName(methID(s, fn, a1, a2, a3, a4, a5, a6), fn, a1, a2, a3, a4, a5, a6)
+	return Func(fn, a1, a2, a3, a4, a5, a6).Method(s).withRenderedArgs()
 }
 
 // Wraps a function with six parameters for the dependency handler.
 func Fn6[T fn6[A, B, C, D, E, F], A, B, C, D, E, F any](fn T, a1 A, a2 B, a3 C, a4 D, a5 E, a6 F) Dependency {
-	return Fn6WithName(funcID(fn, a1, a2, a3, a4, a5, a6), fn, a1, a2, a3, a4, a5, a6)
+	return Func(fn, a1, a2, a3, a4, a5, a6).withRenderedArgs()
 }
 
 // Wraps a function with six parameters and a specific name for the dependency handler.
 func Fn6WithName[T fn6[A, B, C, D, E, F], A, B, C, D, E, F any](
 	name string, fn T, a1 A, a2 B, a3 C, a4 D, a5 E, a6 F,
 ) Dependency {
-	return &dep{
-		id: name,
-		run: func(ctx context.Context) error {
-			switch v := any(fn).(type) {
-			case func(A, B, C, D, E, F):
-				v(a1, a2, a3, a4, a5, a6)
-			case func(A, B, C, D, E, F) error:
-				return v(a1, a2, a3, a4, a5, a6)
-			case func(context.Context, A, B, C, D, E, F):
-				v(ctx, a1, a2, a3, a4, a5, a6)
-			case func(context.Context, A, B, C, D, E, F) error:
-				return v(ctx, a1, a2, a3, a4, a5, a6)
-			}
-			return nil
-		},
-	}
+	return Func(fn, a1, a2, a3, a4, a5, a6).WithID(name)
 }
 
 type selfIdentifier interface { //nolint: iface
 	ID() string
 }
 
+// returns a string that can be used to identify the given method and arguments.
 func methID(thing, fn any, args ...any) string {
+	return methName(thing, fn) + renderedArgs(args...)
+}
+
+// returns the name of the method fn, prefixed with the ID of thing.
+func methName(thing, fn any) string {
 	sid := structID(thing)
-	fid := funcID(fn, args...)
+	fid := funcName(fn)
 	idx := strings.LastIndex(fid, ").")
 	if idx >= 0 {
-		// if the function receiver is a pointer, fid will look like "main.(*CI).PreCommit([]string{})"
+		// if the function receiver is a pointer, fid will look like "main.(*CI).PreCommit"
 		// we want to remove the "main.(*CI)" part to replace it with sid
 		fid = fid[idx+2:]
 	} else {
@@ -445,7 +527,7 @@ func methID(thing, fn any, args ...any) string {
 		}
 		idx = strings.LastIndex(fid, sidSlice)
 		if idx >= 0 {
-			// if the function receiver is not a pointer, fid will look like "main.Lint.glciFix()"
+			// if the function receiver is not a pointer, fid will look like "main.Lint.glciFix"
 			// we want to remove the "main.Lint" part to replace it with sid
 			fid = fid[(idx + len(sidSlice) + 1):]
 		}
@@ -454,48 +536,19 @@ func methID(thing, fn any, args ...any) string {
 }
 
 func methIDLit(thing any, fn string, args ...any) string {
-	sid := structID(thing)
-	if len(args) > 0 {
-		argStrings := make([]string, len(args))
-		for i, arg := range args {
-			argStrings[i] = fmt.Sprintf("%#v", arg)
-		}
-		return fmt.Sprintf("%s.%s(%s)", sid, fn, strings.Join(argStrings, ", "))
-	}
-	return fmt.Sprintf("%s.%s()", sid, fn)
-}
-
-// returns a string to identify a struct.
-func structID(thing any) string {
-	if sid, ok := thing.(selfIdentifier); ok {
-		return sid.ID()
-	}
-
-	thingType := reflect.TypeOf(thing)
-	if thingType.Kind() == reflect.Pointer {
-		thingType = thingType.Elem()
-	}
-	if thingType.Kind() != reflect.Struct {
-		// TODO: error?
-		return ""
-	}
-	fields := strings.TrimPrefix(fmt.Sprintf("%+v", thing), "&")
-	return fmt.Sprintf("%s.%s%s", thingType.PkgPath(), thingType.Name(), fields)
+	return fmt.Sprintf("%s.%s%s", structID(thing), fn, renderedArgs(args...))
 }
 
 // returns a string that can be used to identify the given function and arguments.
 func funcID(fn any, args ...any) string {
+	return funcName(fn) + renderedArgs(args...)
+}
+
+// returns the fully qualified name of the given function.
+func funcName(fn any) string {
 	fnV := reflect.ValueOf(fn)
 	fnR := runtime.FuncForPC(fnV.Pointer())
-	name := strings.TrimSuffix(fnR.Name(), "-fm")
-	if len(args) > 0 {
-		argStrings := make([]string, len(args))
-		for i, arg := range args {
-			argStrings[i] = fmt.Sprintf("%#v", arg)
-		}
-		return fmt.Sprintf("%s(%s)", name, strings.Join(argStrings, ", "))
-	}
-	return name + "()"
+	return strings.TrimSuffix(fnR.Name(), "-fm")
 }
 
 // container type to ensure a dependency only runs once.
@@ -504,6 +557,9 @@ type depOnce struct {
 	dep  Dependency
 	took time.Duration
 	err  error
+	// result of ResultDependencies.
+	value    any
+	hasValue bool
 }
 
 func newOnce(dep Dependency) *depOnce {
@@ -517,6 +573,14 @@ func (o *depOnce) ID() string {
 	return o.dep.ID()
 }
 
+func (o *depOnce) DisplayName() string {
+	return displayName(o.dep)
+}
+
+func (o *depOnce) Tags() []string {
+	return tagsOf(o.dep)
+}
+
 func (o *depOnce) Run(ctx context.Context) error {
 	o.once.Do(func() {
 		start := time.Now()
@@ -538,7 +602,14 @@ func (o *depOnce) Run(ctx context.Context) error {
 			}
 		}()
 
-		o.err = o.dep.Run(ctx)
+		runCtx := contextWithCall(ctx, o.ID())
+		value, ok, err := runResult(runCtx, o.dep)
+		if ok {
+			o.value, o.err = value, err
+			o.hasValue = o.err == nil
+		} else {
+			o.err = o.dep.Run(runCtx)
+		}
 		o.took = time.Since(start)
 	})
 	return o.err
@@ -1,8 +1,11 @@
 package run
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -274,3 +277,100 @@ func Test_methID(t *testing.T) {
 	s = methID(test, test.privateReceiverNotPointer)
 	assert.Equal(t, "pkg.package-operator.run/cardboard/run.MyThing{field:xxx}.privateReceiverNotPointer()", s)
 }
+
+func Test_dependencyRun_cycle(t *testing.T) {
+	t.Parallel()
+
+	t.Run("serial", func(t *testing.T) {
+		t.Parallel()
+		dr := newDependencyRun()
+		var a, b Dependency
+		a = FnWithName("a", func(ctx context.Context) error {
+			return dr.Serial(ctx, a, b)
+		})
+		b = FnWithName("b", func(ctx context.Context) error {
+			return dr.Serial(ctx, b, a)
+		})
+
+		err := runWithTimeout(t, func() error {
+			return dr.Serial(t.Context(), DependencyID("_test"), a)
+		})
+		require.EqualError(t, err, "running a: running b: running a: dependency cycle detected: a -> b -> a")
+		var cycleErr *DependencyCycleError
+		require.ErrorAs(t, err, &cycleErr)
+		assert.Equal(t, []string{"a", "b", "a"}, cycleErr.Path)
+	})
+
+	t.Run("self", func(t *testing.T) {
+		t.Parallel()
+		dr := newDependencyRun()
+		var a Dependency
+		a = FnWithName("a", func(ctx context.Context) error {
+			return dr.Parallel(ctx, a, a)
+		})
+
+		err := runWithTimeout(t, func() error {
+			return dr.Serial(t.Context(), DependencyID("_test"), a)
+		})
+		require.EqualError(t, err, "running a: running a: dependency cycle detected: a -> a")
+	})
+
+	t.Run("parallel", func(t *testing.T) {
+		t.Parallel()
+		dr := newDependencyRun()
+		var a, b, c Dependency
+		a = FnWithName("a", func(ctx context.Context) error {
+			return dr.Parallel(ctx, a, b, c)
+		})
+		b = FnWithName("b", func(ctx context.Context) error {
+			time.Sleep(10 * time.Millisecond)
+			return dr.Serial(ctx, b, c)
+		})
+		c = FnWithName("c", func(ctx context.Context) error {
+			time.Sleep(10 * time.Millisecond)
+			return dr.Serial(ctx, c, b)
+		})
+
+		err := runWithTimeout(t, func() error {
+			return dr.Serial(t.Context(), DependencyID("_test"), a)
+		})
+		var cycleErr *DependencyCycleError
+		require.ErrorAs(t, err, &cycleErr)
+	})
+}
+
+func runWithTimeout(t *testing.T, fn func() error) error {
+	t.Helper()
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- fn() }()
+	select {
+	case err := <-errCh:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout, dependency run is deadlocked")
+		return nil
+	}
+}
+
+func Test_dependencyRun_reportTags(t *testing.T) {
+	t.Parallel()
+
+	dr := newDependencyRun()
+	dr.noColor = true
+	require.NoError(t, dr.Serial(t.Context(), DependencyID("_test"),
+		Func(func() {}).WithID("e2e").WithTags("slow", "e2e"),
+		FnWithName("unit", func(context.Context) error { return nil }),
+	))
+
+	assert.Contains(t, dr.Report(), "[OK] e2e [slow, e2e] [took ")
+	assert.Contains(t, dr.Report(), "[OK] unit [took ")
+
+	data, err := dr.ReportJSON()
+	require.NoError(t, err)
+	var nodes []reportNode
+	require.NoError(t, json.Unmarshal(data, &nodes))
+	require.Len(t, nodes, 2)
+	assert.Equal(t, []string{"slow", "e2e"}, nodes[0].Tags)
+	assert.Nil(t, nodes[1].Tags)
+}
@@ -1,6 +1,9 @@
 package run
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // used to wrap unexpected panics.
 type internalPanickedError struct {
@@ -24,3 +27,35 @@ type UnknownTargetError struct {
 func (t *UnknownTargetError) Error() string {
 	return fmt.Sprintf("unknown target: %q", t.ID)
 }
+
+// MissingToolsError is raised when tools need to be installed offline, but are not available.
+type MissingToolsError struct {
+	// Directory tools are installed from.
+	Source string
+	// Missing tools as package@version or download URL.
+	Tools []string
+}
+
+func (m *MissingToolsError) Error() string {
+	return fmt.Sprintf("offline mode: tools not available in %s: %s", m.Source, strings.Join(m.Tools, ", "))
+}
+
+// UnknownToolError is raised when a tool is requested that was never registered.
+type UnknownToolError struct {
+	Tool string
+}
+
+func (t *UnknownToolError) Error() string {
+	return fmt.Sprintf("unknown tool: %q", t.Tool)
+}
+
+// DependencyCycleError is returned when a dependency directly or indirectly depends on itself.
+type DependencyCycleError struct {
+	// IDs of the dependencies forming the cycle.
+	// The first and last element are the same.
+	Path []string
+}
+
+func (e *DependencyCycleError) Error() string {
+	return "dependency cycle detected: " + strings.Join(e.Path, " -> ")
+}
@@ -0,0 +1,50 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Interval in which an already held file lock is retried.
+const fileLockRetryInterval = 50 * time.Millisecond
+
+// Acquires an exclusive lock on the given file, waiting until it becomes available or ctx is done.
+// Used to serialize tool installs across concurrent cardboard processes.
+func lockFile(ctx context.Context, file string) (unlock func() error, err error) {
+	if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("creating lock directory: %w", err)
+	}
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening lock file: %w", err)
+	}
+
+	ticker := time.NewTicker(fileLockRetryInterval)
+	defer ticker.Stop()
+	for {
+		locked, err := tryLockFile(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("locking %s: %w", file, err)
+		}
+		if locked {
+			return func() error {
+				if err := unlockFile(f); err != nil {
+					f.Close()
+					return fmt.Errorf("unlocking %s: %w", file, err)
+				}
+				return f.Close()
+			}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			f.Close()
+			return nil, fmt.Errorf("waiting for lock %s: %w", file, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
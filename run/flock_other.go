@@ -0,0 +1,11 @@
+//go:build !unix
+
+package run
+
+import "os"
+
+// File locking is not supported on this platform,
+// installs are only serialized within a single process.
+func tryLockFile(*os.File) (bool, error) { return true, nil }
+
+func unlockFile(*os.File) error { return nil }
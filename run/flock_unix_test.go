@@ -0,0 +1,37 @@
+//go:build unix
+
+package run
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_lockFile(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), "locks", "tool.lock")
+	unlock, err := lockFile(t.Context(), file)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 3*fileLockRetryInterval)
+	defer cancel()
+	_, err = lockFile(ctx, file)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	acquired := make(chan error)
+	go func() {
+		unlock, err := lockFile(t.Context(), file)
+		if err == nil {
+			err = unlock()
+		}
+		acquired <- err
+	}()
+	time.Sleep(fileLockRetryInterval)
+	require.NoError(t, unlock())
+	require.NoError(t, <-acquired)
+}
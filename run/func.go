@@ -0,0 +1,238 @@
+package run
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FuncDep is a Dependency calling a function with arbitrary arguments.
+// Use Func to create one and the With* methods to customize it.
+//
+// Example:
+// run.Func(l.goModTidy, "./kubeutils/").Method(l).WithTimeout(time.Minute).
+type FuncDep struct {
+	fn       reflect.Value
+	args     []any
+	receiver any
+	idArgs   []any
+	id       string
+	name     string
+	timeout  time.Duration
+	tags     []string
+	// identify by rendered instead of hashed arguments.
+	rendered bool
+	// error validating fn and args.
+	err error
+}
+
+var _ Dependency = (*FuncDep)(nil)
+
+var (
+	contextType = reflect.TypeFor[context.Context]()
+	errorType   = reflect.TypeFor[error]()
+)
+
+// Func wraps fn and the given arguments for the dependency handler.
+// fn may optionally take a context.Context as first parameter and may optionally return an error.
+// By default the dependency ID is composed of the function name and a hash of all arguments,
+// so large or unprintable arguments keep IDs short and stable.
+func Func(fn any, args ...any) *FuncDep {
+	d := &FuncDep{
+		fn:   reflect.ValueOf(fn),
+		args: args,
+	}
+	d.err = d.validate()
+	return d
+}
+
+// Method identifies the dependency as method of the given struct.
+// The ID of the dependency will be derived from the struct instead of the function receiver.
+func (d *FuncDep) Method(receiver any) *FuncDep {
+	d.receiver = receiver
+	return d
+}
+
+// WithID sets a custom ID, overriding the ID derived from the function and its arguments.
+func (d *FuncDep) WithID(id string) *FuncDep {
+	d.id = id
+	return d
+}
+
+// WithDisplayName sets a short human-readable name to use in reports.
+func (d *FuncDep) WithDisplayName(name string) *FuncDep {
+	d.name = name
+	return d
+}
+
+// WithIDArgs identifies the dependency by a hash of the given values instead of all call arguments.
+func (d *FuncDep) WithIDArgs(args ...any) *FuncDep {
+	if args == nil {
+		args = []any{}
+	}
+	d.idArgs = args
+	return d
+}
+
+// Identifies the dependency by rendering all call arguments, like the typed Fn and Meth helpers do.
+func (d *FuncDep) withRenderedArgs() *FuncDep {
+	d.rendered = true
+	return d
+}
+
+// WithTimeout cancels the context passed to the function after the given duration.
+func (d *FuncDep) WithTimeout(timeout time.Duration) *FuncDep {
+	d.timeout = timeout
+	return d
+}
+
+// WithTags attaches the given tags to the dependency, they are shown in reports.
+func (d *FuncDep) WithTags(tags ...string) *FuncDep {
+	d.tags = append(d.tags, tags...)
+	return d
+}
+
+// Tags returns the tags attached to the dependency.
+func (d *FuncDep) Tags() []string {
+	return slices.Clone(d.tags)
+}
+
+func (d *FuncDep) ID() string {
+	if len(d.id) > 0 {
+		return d.id
+	}
+	if !d.fn.IsValid() || d.fn.Kind() != reflect.Func {
+		return "invalid" + hashedArgs(d.args...)
+	}
+
+	var name string
+	if d.receiver != nil {
+		name = methName(d.receiver, d.fn.Interface())
+	} else {
+		name = funcName(d.fn.Interface())
+	}
+	switch {
+	case d.idArgs != nil:
+		return name + hashedArgs(d.idArgs...)
+	case d.rendered:
+		return name + renderedArgs(d.args...)
+	default:
+		return name + hashedArgs(d.args...)
+	}
+}
+
+func (d *FuncDep) DisplayName() string {
+	if len(d.name) > 0 {
+		return d.name
+	}
+	return shortID(d.ID())
+}
+
+func (d *FuncDep) Run(ctx context.Context) error {
+	if d.err != nil {
+		return d.err
+	}
+	if d.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+
+	fnType := d.fn.Type()
+	in := make([]reflect.Value, 0, len(d.args)+1)
+	offset := 0
+	if fnType.NumIn() > 0 && fnType.In(0) == contextType {
+		in = append(in, reflect.ValueOf(ctx))
+		offset = 1
+	}
+	for i, arg := range d.args {
+		if arg == nil {
+			in = append(in, reflect.Zero(paramType(fnType, i+offset)))
+			continue
+		}
+		in = append(in, reflect.ValueOf(arg))
+	}
+
+	out := d.fn.Call(in)
+	if len(out) == 0 || out[0].IsNil() {
+		return nil
+	}
+	err := out[0].Interface().(error)
+	if d.timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s: %w", d.timeout, err)
+	}
+	return err
+}
+
+// checks that fn can be called with the given arguments.
+func (d *FuncDep) validate() error {
+	if !d.fn.IsValid() {
+		return errors.New("expected function, got nil")
+	}
+	if d.fn.Kind() != reflect.Func {
+		return fmt.Errorf("expected function, got %s", d.fn.Type())
+	}
+
+	fnType := d.fn.Type()
+	if fnType.NumOut() > 1 ||
+		(fnType.NumOut() == 1 && fnType.Out(0) != errorType) {
+		return fmt.Errorf("%s must return nothing or a single error", fnType)
+	}
+
+	offset := 0
+	if fnType.NumIn() > 0 && fnType.In(0) == contextType {
+		offset = 1
+	}
+	numIn := fnType.NumIn() - offset
+	if (!fnType.IsVariadic() && len(d.args) != numIn) ||
+		(fnType.IsVariadic() && len(d.args) < numIn-1) {
+		return fmt.Errorf("%s called with %d arguments", fnType, len(d.args))
+	}
+	for i, arg := range d.args {
+		pt := paramType(fnType, i+offset)
+		if arg == nil {
+			switch pt.Kind() {
+			case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Pointer, reflect.Slice:
+				continue
+			default:
+				return fmt.Errorf("%s argument %d: nil is not assignable to %s", fnType, i, pt)
+			}
+		}
+		if at := reflect.TypeOf(arg); !at.AssignableTo(pt) {
+			return fmt.Errorf("%s argument %d: %s is not assignable to %s", fnType, i, at, pt)
+		}
+	}
+	return nil
+}
+
+// Returns the type of the i-th parameter, taking variadic functions into account.
+func paramType(fnType reflect.Type, i int) reflect.Type {
+	if fnType.IsVariadic() && i >= fnType.NumIn()-1 {
+		return fnType.In(fnType.NumIn() - 1).Elem()
+	}
+	return fnType.In(i)
+}
+
+// Renders arguments for use in IDs.
+func renderedArgs(args ...any) string {
+	argStrings := make([]string, len(args))
+	for i, arg := range args {
+		argStrings[i] = renderArg(arg)
+	}
+	return "(" + strings.Join(argStrings, ", ") + ")"
+}
+
+// Hashes arguments for use in IDs.
+func hashedArgs(args ...any) string {
+	h := sha256.New()
+	for _, arg := range args {
+		fmt.Fprintf(h, "%s\x00", renderArg(arg))
+	}
+	return "(" + hex.EncodeToString(h.Sum(nil))[:12] + ")"
+}
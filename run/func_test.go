@@ -0,0 +1,115 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFunc(t *testing.T) {
+	t.Parallel()
+
+	var got []any
+	fn := func(_ context.Context, a string, b int, c bool, d, e, f, g string) error {
+		got = []any{a, b, c, d, e, f, g}
+		return nil
+	}
+
+	dep := Func(fn, "a", 1, true, "d", "e", "f", "g")
+	require.NoError(t, dep.Run(t.Context()))
+	assert.Equal(t, []any{"a", 1, true, "d", "e", "f", "g"}, got)
+	assert.Regexp(t, `^pkg\.package-operator\.run/cardboard/run\.TestFunc\.func1\([0-9a-f]{12}\)$`, dep.ID())
+}
+
+func TestFunc_variadicAndNil(t *testing.T) {
+	t.Parallel()
+
+	var got []string
+	fn := func(err error, args ...string) error {
+		got = args
+		return err
+	}
+
+	require.NoError(t, Func(fn, nil, "a", "b").Run(t.Context()))
+	assert.Equal(t, []string{"a", "b"}, got)
+	require.ErrorIs(t, Func(fn, errTest).Run(t.Context()), errTest)
+}
+
+func TestFunc_ID(t *testing.T) {
+	t.Parallel()
+
+	thing := &MyThing{field: "xxx"}
+
+	assert.Equal(t, "custom", Func(myFunc).WithID("custom").ID())
+	hashed := Func(thing.private, "banana").Method(thing)
+	assert.Equal(t,
+		"pkg.package-operator.run/cardboard/run.MyThing{field:xxx}.private(179fc14a089b)",
+		hashed.ID(), "arguments are hashed by default")
+	assert.NotEqual(t, hashed.ID(), Func(thing.private, "other").Method(thing).ID())
+	assert.Equal(t, hashed.ID(),
+		Func(thing.private, "other").Method(thing).WithIDArgs("banana").ID())
+	assert.True(t, strings.HasSuffix(Func(myFunc).WithIDArgs().ID(), "myFunc(e3b0c44298fc)"))
+}
+
+func TestFunc_timeout(t *testing.T) {
+	t.Parallel()
+
+	dep := Func(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}).WithTimeout(10 * time.Millisecond).WithTags("slow")
+
+	err := dep.Run(t.Context())
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.EqualError(t, err, "timed out after 10ms: context deadline exceeded")
+	assert.Equal(t, []string{"slow"}, dep.Tags())
+}
+
+func TestFunc_invalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		dep  *FuncDep
+		err  string
+	}{
+		{
+			name: "not a function",
+			dep:  Func("banana"),
+			err:  "expected function, got string",
+		},
+		{
+			name: "wrong return",
+			dep:  Func(func() string { return "" }),
+			err:  "func() string must return nothing or a single error",
+		},
+		{
+			name: "wrong arity",
+			dep:  Func(func(_ string) {}),
+			err:  "func(string) called with 0 arguments",
+		},
+		{
+			name: "wrong type",
+			dep:  Func(func(_ string) {}, 42),
+			err:  "func(string) argument 0: int is not assignable to string",
+		},
+		{
+			name: "nil to value",
+			dep:  Func(func(_ string) {}, nil),
+			err:  "func(string) argument 0: nil is not assignable to string",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			err := test.dep.Run(t.Context())
+			require.EqualError(t, err, test.err)
+			assert.False(t, errors.Is(err, errTest))
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package run
+
+import (
+	"context"
+	"debug/buildinfo"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+
+	"pkg.package-operator.run/cardboard/sh"
+)
+
+// A go tool declared via a tool directive in a go.mod file.
+type goModTool struct {
+	// Name of the installed binary.
+	Name       string
+	PackageURL string
+	// Version without "v" prefix.
+	Version string
+	// Path of the module providing the tool.
+	Module string
+	// Directory of the go.mod file, tools are built within this module.
+	Dir string
+	// Module path -> version of all require directives, the build list of tools.
+	Requires map[string]string
+}
+
+// Reads tool directives from the given go.mod file and
+// resolves their versions from the require directives of the module providing them.
+func readGoModTools(goModFile string) ([]goModTool, error) {
+	data, err := os.ReadFile(goModFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading go.mod: %w", err)
+	}
+	f, err := modfile.Parse(goModFile, data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("parsing go.mod: %w", err)
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(goModFile))
+	if err != nil {
+		return nil, err
+	}
+	requires := make(map[string]string, len(f.Require))
+	for _, r := range f.Require {
+		requires[r.Mod.Path] = r.Mod.Version
+	}
+
+	tools := make([]goModTool, 0, len(f.Tool))
+	for _, t := range f.Tool {
+		req := providingModule(f.Require, t.Path)
+		if req == nil {
+			return nil, fmt.Errorf("%s: no required module provides tool %s", goModFile, t.Path)
+		}
+		tools = append(tools, goModTool{
+			Name:       goToolName(t.Path),
+			PackageURL: t.Path,
+			Version:    strings.TrimPrefix(req.Mod.Version, "v"),
+			Module:     req.Mod.Path,
+			Dir:        dir,
+			Requires:   requires,
+		})
+	}
+	return tools, nil
+}
+
+// Returns the require directive of the module containing pkg, preferring the longest module path.
+func providingModule(requires []*modfile.Require, pkg string) *modfile.Require {
+	var match *modfile.Require
+	for _, r := range requires {
+		if pkg != r.Mod.Path && !strings.HasPrefix(pkg, r.Mod.Path+"/") {
+			continue
+		}
+		if match == nil || len(r.Mod.Path) > len(match.Mod.Path) {
+			match = r
+		}
+	}
+	return match
+}
+
+// Returns the binary name go install uses for the given package,
+// e.g. "example.com/cmd/tool/v2" -> "tool".
+func goToolName(pkg string) string {
+	if prefix, major, ok := module.SplitPathVersion(pkg); ok && strings.HasPrefix(major, "/") {
+		return path.Base(prefix)
+	}
+	return path.Base(pkg)
+}
+
+// Register all tools declared via tool directives in the given go.mod file.
+// Versions are taken from the require directives, so they are declared in one place.
+// Tools are built within the module, so they use its build list and go.sum like "go tool" does.
+func (d *dependencyManager) RegisterGoModTools(ctx context.Context, goModFile string) error {
+	tools, err := readGoModTools(goModFile)
+	if err != nil {
+		return err
+	}
+	for _, t := range tools {
+		newURL := depURL(t.PackageURL, t.Version)
+		if url, ok := d.deps[t.Name]; ok && newURL != url {
+			return fmt.Errorf("conflicting dependency for %s, already have: %s", t.Name, url)
+		}
+		buildFn := func() error {
+			return d.goBuildTool(ctx, t)
+		}
+		d.deps[t.Name] = newURL
+		d.versions[t.Name] = "v" + t.Version
+		d.modTools[t.Name] = t
+		d.add(t.Name, FnWithName("go build "+t.Name, buildFn))
+	}
+	return nil
+}
+
+// go build a tool declared in a go.mod file into the dependency directory.
+func (d *dependencyManager) goBuildTool(ctx context.Context, t goModTool) error {
+	if d.dryRun {
+		d.logger.InfoContext(ctx, "skipping tool install in dry run", "tool", t.Name)
+		return nil
+	}
+	if err := os.MkdirAll(d.Bin(), os.ModePerm); err != nil {
+		return fmt.Errorf("create dependency dir: %w", err)
+	}
+	unlock, err := d.lock(ctx, t.Name)
+	if err != nil {
+		return err
+	}
+	defer unlock() //nolint:errcheck
+
+	reason := goModToolInstallReason(path.Join(d.Bin(), t.Name), t)
+	if len(reason) == 0 {
+		return nil
+	}
+
+	d.logger.InfoContext(ctx, "building tool", "tool", t.Name, "version", "v"+t.Version, "reason", reason)
+	// Only the module declaring the tool determines its dependencies, not a surrounding workspace.
+	env := map[string]string{"GOWORK": "off"}
+	if d.offline {
+		if err := d.checkOffline(ctx, t.Name); err != nil {
+			return err
+		}
+		source, err := d.offlineSource(ctx)
+		if err != nil {
+			return err
+		}
+		maps.Copy(env, offlineEnv(source, d.verifyLock))
+	}
+
+	// Build into a temporary directory and move the binary into place,
+	// so concurrent readers never see a partially written binary.
+	tmpBin, err := os.MkdirTemp(d.path, ".install-"+t.Name+"-*")
+	if err != nil {
+		return fmt.Errorf("create temporary bin dir: %w", err)
+	}
+	defer os.RemoveAll(tmpBin)
+	tmpFile := filepath.Join(tmpBin, t.Name)
+	if err := d.runner.New(sh.WithEnvironment(env), sh.WithWorkDir(t.Dir)).
+		Run(ctx, "go", "build", "-o", tmpFile, t.PackageURL); err != nil {
+		return fmt.Errorf("build %s: %w", t.PackageURL, err)
+	}
+	if err := os.Rename(tmpFile, path.Join(d.Bin(), t.Name)); err != nil {
+		return fmt.Errorf("build %s: %w", t.PackageURL, err)
+	}
+	return nil
+}
+
+// Returns why the given tool binary needs to be (re)built or an empty string if it is up to date.
+// The binary is up to date, if all modules in its build info have the version required by the go.mod file.
+func goModToolInstallReason(bin string, t goModTool) string {
+	bi, err := buildinfo.ReadFile(bin)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return "not installed"
+	case err != nil:
+		return fmt.Sprintf("reading build info: %v", err)
+	case bi.Path != t.PackageURL:
+		return fmt.Sprintf("installed from %s, want %s", bi.Path, t.PackageURL)
+	}
+	found := false
+	for _, m := range append([]*debug.Module{&bi.Main}, bi.Deps...) {
+		if m.Path == t.Module {
+			found = true
+		}
+		if want, ok := t.Requires[m.Path]; ok && m.Version != want {
+			return fmt.Sprintf("built with %s %s, go.mod requires %s", m.Path, m.Version, want)
+		}
+	}
+	if !found {
+		return fmt.Sprintf("built without %s", t.Module)
+	}
+	return ""
+}
+
+// Returns why the given go tool needs to be (re)installed or an empty string if it is up to date.
+func (d *dependencyManager) goToolReason(tool string) string {
+	bin := path.Join(d.Bin(), tool)
+	if t, ok := d.modTools[tool]; ok {
+		return goModToolInstallReason(bin, t)
+	}
+	packageURL, _, _ := strings.Cut(d.deps[tool], "@")
+	return goToolInstallReason(bin, packageURL, strings.TrimPrefix(d.versions[tool], "v"))
+}
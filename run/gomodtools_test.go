@@ -0,0 +1,109 @@
+package run
+
+import (
+	"debug/buildinfo"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_readGoModTools(t *testing.T) {
+	t.Parallel()
+
+	goMod := filepath.Join(t.TempDir(), "go.mod")
+	require.NoError(t, os.WriteFile(goMod, []byte(`module example.com/project
+
+go 1.26.0
+
+tool (
+	example.com/tools/cmd/lint
+	example.com/tools/nested/cmd/gen/v2
+)
+
+require (
+	example.com/tools v1.2.3 // indirect
+	example.com/tools/nested v0.4.0 // indirect
+)
+`), 0o644))
+
+	tools, err := readGoModTools(goMod)
+	require.NoError(t, err)
+	requires := map[string]string{"example.com/tools": "v1.2.3", "example.com/tools/nested": "v0.4.0"}
+	dir := filepath.Dir(goMod)
+	assert.Equal(t, []goModTool{
+		{
+			Name: "lint", PackageURL: "example.com/tools/cmd/lint", Version: "1.2.3",
+			Module: "example.com/tools", Dir: dir, Requires: requires,
+		},
+		{
+			Name: "gen", PackageURL: "example.com/tools/nested/cmd/gen/v2", Version: "0.4.0",
+			Module: "example.com/tools/nested", Dir: dir, Requires: requires,
+		},
+	}, tools)
+
+	dm, err := newDependencyManager(newDependencyRun(), t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, dm.RegisterGoModTools(t.Context(), goMod))
+	assert.Equal(t, map[string]string{
+		"lint": "example.com/tools/cmd/lint@v1.2.3",
+		"gen":  "example.com/tools/nested/cmd/gen/v2@v0.4.0",
+	}, dm.deps)
+	assert.Equal(t, tools[0], dm.modTools["lint"])
+}
+
+func Test_goModToolInstallReason(t *testing.T) {
+	t.Parallel()
+
+	// The test binary is built within this module, like tools declared in go.mod.
+	bin, err := os.Executable()
+	require.NoError(t, err)
+	bi, err := buildinfo.ReadFile(bin)
+	require.NoError(t, err)
+	require.NotEmpty(t, bi.Deps)
+	dep := bi.Deps[0]
+
+	tool := goModTool{PackageURL: bi.Path, Module: dep.Path, Requires: map[string]string{dep.Path: dep.Version}}
+	assert.Empty(t, goModToolInstallReason(bin, tool))
+
+	tool.Requires = map[string]string{dep.Path: "v0.0.0-other"}
+	assert.Equal(t, "built with "+dep.Path+" "+dep.Version+", go.mod requires v0.0.0-other",
+		goModToolInstallReason(bin, tool))
+
+	tool.Requires = nil
+	tool.Module = "example.com/missing"
+	assert.Equal(t, "built without example.com/missing", goModToolInstallReason(bin, tool))
+
+	tool.PackageURL = "example.com/other"
+	assert.Equal(t, "installed from "+bi.Path+", want example.com/other", goModToolInstallReason(bin, tool))
+
+	assert.Equal(t, "not installed", goModToolInstallReason(filepath.Join(t.TempDir(), "missing"), tool))
+}
+
+func Test_readGoModTools_errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		goMod string
+		err   string
+	}{
+		{
+			name:  "not required",
+			goMod: "module example.com/project\n\ntool example.com/tools/cmd/lint\n",
+			err:   "no required module provides tool example.com/tools/cmd/lint",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			goMod := filepath.Join(t.TempDir(), "go.mod")
+			require.NoError(t, os.WriteFile(goMod, []byte(test.goMod), 0o644))
+			_, err := readGoModTools(goMod)
+			require.ErrorContains(t, err, test.err)
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package run
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Optionally implemented by dependencies to provide a short human-readable name used in reports.
+type DependencyDisplayNamer interface {
+	DisplayName() string
+}
+
+// Optionally implemented by dependencies to label them in reports, e.g. "slow" or "e2e".
+type DependencyTagger interface {
+	Tags() []string
+}
+
+// Struct tag to control which fields make up the ID of a struct.
+// Fields tagged `cardboard:"id"` are the only fields used, if present.
+// Fields tagged `cardboard:"-"` are never used.
+const idTagKey = "cardboard"
+
+// Maximum depth of nested values rendered into IDs.
+const maxIDRenderDepth = 5
+
+// returns a string to identify a struct.
+func structID(thing any) string {
+	if sid, ok := thing.(selfIdentifier); ok {
+		return sid.ID()
+	}
+
+	visited := map[uintptr]struct{}{}
+	thingValue := reflect.ValueOf(thing)
+	thingType := thingValue.Type()
+	if thingType.Kind() == reflect.Pointer {
+		visited[thingValue.Pointer()] = struct{}{}
+		thingType = thingType.Elem()
+		thingValue = thingValue.Elem()
+	}
+	if thingType.Kind() != reflect.Struct {
+		// TODO: error?
+		return ""
+	}
+
+	var fields strings.Builder
+	if thingValue.IsValid() {
+		renderStruct(&fields, thingValue, identityFields(thingType), 0, visited)
+	} else {
+		fields.WriteString("<nil>")
+	}
+	return fmt.Sprintf("%s.%s%s", thingType.PkgPath(), thingType.Name(), fields.String())
+}
+
+// Returns indexes of fields that make up the identity of a struct.
+func identityFields(t reflect.Type) []int {
+	var all, tagged []int
+	for i := range t.NumField() {
+		switch t.Field(i).Tag.Get(idTagKey) {
+		case "-":
+			continue
+		case "id":
+			tagged = append(tagged, i)
+		}
+		all = append(all, i)
+	}
+	if len(tagged) > 0 {
+		return tagged
+	}
+	return all
+}
+
+// Renders arguments for use in IDs.
+// Values without pointers use Go-syntax representation,
+// everything else is rendered without memory addresses.
+func renderArg(arg any) string {
+	if arg == nil || !containsAddress(reflect.TypeOf(arg), map[reflect.Type]struct{}{}) {
+		return fmt.Sprintf("%#v", arg)
+	}
+
+	var b strings.Builder
+	v := reflect.ValueOf(arg)
+	b.WriteString(v.Type().String())
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	renderValue(&b, v, 0, map[uintptr]struct{}{})
+	return b.String()
+}
+
+// Checks whether fmt would render memory addresses for values of the given type.
+func containsAddress(t reflect.Type, seen map[reflect.Type]struct{}) bool {
+	if _, ok := seen[t]; ok {
+		return false
+	}
+	seen[t] = struct{}{}
+
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Func, reflect.Chan, reflect.UnsafePointer, reflect.Interface:
+		return true
+	case reflect.Array, reflect.Slice:
+		return containsAddress(t.Elem(), seen)
+	case reflect.Map:
+		return containsAddress(t.Key(), seen) || containsAddress(t.Elem(), seen)
+	case reflect.Struct:
+		for i := range t.NumField() {
+			if containsAddress(t.Field(i).Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Renders values similar to fmt's %+v verb, but dereferences pointers instead of printing their address.
+func renderValue(b *strings.Builder, v reflect.Value, depth int, visited map[uintptr]struct{}) {
+	if !v.IsValid() {
+		b.WriteString("<nil>")
+		return
+	}
+	if depth > maxIDRenderDepth {
+		b.WriteString("...")
+		return
+	}
+	if i, ok := interfaceOf(v); ok && !isNil(v) {
+		switch i := i.(type) {
+		case DependencyIDer:
+			b.WriteString(i.ID())
+			return
+		case fmt.Stringer:
+			if v.Kind() != reflect.Pointer {
+				b.WriteString(i.String())
+				return
+			}
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			b.WriteString("<nil>")
+			return
+		}
+		if _, ok := visited[v.Pointer()]; ok {
+			b.WriteString("<cycle>")
+			return
+		}
+		visited[v.Pointer()] = struct{}{}
+		defer delete(visited, v.Pointer())
+		b.WriteByte('&')
+		renderValue(b, v.Elem(), depth+1, visited)
+	case reflect.Interface:
+		renderValue(b, v.Elem(), depth, visited)
+	case reflect.Struct:
+		renderStruct(b, v, identityFields(v.Type()), depth, visited)
+	case reflect.Slice, reflect.Array:
+		b.WriteByte('[')
+		for i := range v.Len() {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			renderValue(b, v.Index(i), depth+1, visited)
+		}
+		b.WriteByte(']')
+	case reflect.Map:
+		entries := make([]string, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			var entry strings.Builder
+			renderValue(&entry, iter.Key(), depth+1, visited)
+			entry.WriteByte(':')
+			renderValue(&entry, iter.Value(), depth+1, visited)
+			entries = append(entries, entry.String())
+		}
+		slices.Sort(entries)
+		b.WriteString("map[" + strings.Join(entries, " ") + "]")
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		if v.IsNil() {
+			b.WriteString("<nil>")
+			return
+		}
+		b.WriteString(v.Type().String())
+	case reflect.String:
+		b.WriteString(v.String())
+	case reflect.Bool:
+		fmt.Fprint(b, v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		fmt.Fprint(b, v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		fmt.Fprint(b, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		fmt.Fprint(b, v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		fmt.Fprint(b, v.Complex())
+	default:
+		b.WriteString(v.Type().String())
+	}
+}
+
+func renderStruct(b *strings.Builder, v reflect.Value, fields []int, depth int, visited map[uintptr]struct{}) {
+	b.WriteByte('{')
+	for i, field := range fields {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(v.Type().Field(field).Name)
+		b.WriteByte(':')
+		renderValue(b, v.Field(field), depth+1, visited)
+	}
+	b.WriteByte('}')
+}
+
+// Returns the value as interface, even for basic kinds obtained from unexported struct fields.
+func interfaceOf(v reflect.Value) (any, bool) {
+	if v.CanInterface() {
+		return v.Interface(), true
+	}
+
+	var c reflect.Value
+	switch v.Kind() {
+	case reflect.String:
+		c = reflect.ValueOf(v.String())
+	case reflect.Bool:
+		c = reflect.ValueOf(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		c = reflect.ValueOf(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		c = reflect.ValueOf(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		c = reflect.ValueOf(v.Float())
+	default:
+		return nil, false
+	}
+	return c.Convert(v.Type()).Interface(), true
+}
+
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	}
+	return false
+}
+
+// Returns a short name for the given dependency to use in reports.
+func displayName(dep DependencyIDer) string {
+	if namer, ok := dep.(DependencyDisplayNamer); ok {
+		if name := namer.DisplayName(); len(name) > 0 {
+			return name
+		}
+	}
+	return shortID(dep.ID())
+}
+
+// Returns the tags of the given dependency, if any.
+func tagsOf(dep DependencyIDer) []string {
+	if tagger, ok := dep.(DependencyTagger); ok {
+		return tagger.Tags()
+	}
+	return nil
+}
+
+// Strips the package path from the qualified identifier at the start of an ID.
+// e.g. "pkg.package-operator.run/cardboard/run.MyThing{field:xxx}.Test()" -> "run.MyThing{field:xxx}.Test()".
+func shortID(id string) string {
+	ident := id
+	if i := strings.IndexAny(id, "{("); i >= 0 {
+		ident = id[:i]
+	}
+	if i := strings.LastIndex(ident, "/"); i >= 0 {
+		return id[i+1:]
+	}
+	return id
+}
@@ -0,0 +1,102 @@
+package run
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type idTestInner struct {
+	Name string
+}
+
+type idTestThing struct {
+	name    string
+	inner   *idTestInner
+	timeout time.Duration
+	labels  map[string]string
+	fn      func()
+	self    *idTestThing
+}
+
+type idTestTagged struct {
+	name  string `cardboard:"id"`
+	cache *idTestInner
+}
+
+type idTestExcluded struct {
+	name  string
+	cache *idTestInner `cardboard:"-"`
+}
+
+func Test_structID_stable(t *testing.T) {
+	t.Parallel()
+
+	newThing := func() *idTestThing {
+		thing := &idTestThing{
+			name:    "banana",
+			inner:   &idTestInner{Name: "inner"},
+			timeout: time.Minute,
+			labels:  map[string]string{"b": "2", "a": "1"},
+			fn:      func() {},
+		}
+		thing.self = thing
+		return thing
+	}
+
+	id := structID(newThing())
+	assert.Equal(t, structID(newThing()), id)
+	assert.Equal(t,
+		"pkg.package-operator.run/cardboard/run.idTestThing"+
+			"{name:banana inner:&{Name:inner} timeout:1m0s labels:map[a:1 b:2] fn:func() self:<cycle>}", id)
+}
+
+func Test_structID_tags(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "pkg.package-operator.run/cardboard/run.idTestTagged{name:a}",
+		structID(&idTestTagged{name: "a", cache: &idTestInner{}}))
+	assert.Equal(t, "pkg.package-operator.run/cardboard/run.idTestExcluded{name:a}",
+		structID(idTestExcluded{name: "a", cache: &idTestInner{}}))
+}
+
+func Test_renderArg(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, `[]string{"a"}`, renderArg([]string{"a"}))
+	assert.Equal(t, `"a"`, renderArg("a"))
+	assert.Equal(t, "<nil>", renderArg(nil))
+	assert.Equal(t, "*run.idTestInner{Name:a}", renderArg(&idTestInner{Name: "a"}))
+	assert.Equal(t, "[]*run.idTestInner[&{Name:a} <nil>]", renderArg([]*idTestInner{{Name: "a"}, nil}))
+}
+
+func Test_displayName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		id       string
+		expected string
+	}{
+		{
+			id:       "pkg.package-operator.run/cardboard/run.MyThing{field:xxx}.Test(\"./a/b\")",
+			expected: "run.MyThing{field:xxx}.Test(\"./a/b\")",
+		},
+		{
+			id:       "pkg.package-operator.run/cardboard/run.(*MyTestType).Test3(\"1\", 42, true)",
+			expected: "run.(*MyTestType).Test3(\"1\", 42, true)",
+		},
+		{
+			id:       "go install gotestfmt",
+			expected: "go install gotestfmt",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.id, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.expected, displayName(DependencyID(test.id)))
+		})
+	}
+
+	assert.Equal(t, "short", displayName(Func(myFunc).WithDisplayName("short")))
+}
@@ -0,0 +1,216 @@
+package run
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"debug/buildinfo"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"strings"
+
+	"pkg.package-operator.run/cardboard/cache"
+)
+
+// Name of the lockfile in the project root, listing checksums of all registered tools.
+const LockfileName = "cardboard.lock"
+
+// LockfileMode controls how the lockfile is used when installing tools.
+type LockfileMode string
+
+const (
+	// Ignore the lockfile.
+	LockfileModeOff LockfileMode = "off"
+	// Fail if an installed tool does not match the lockfile.
+	LockfileModeVerify LockfileMode = "verify"
+)
+
+const lockfileHeader = "# Generated by cardboard, update with \"tools lock\". DO NOT EDIT.\n"
+
+// A single line in the lockfile.
+type lockEntry struct {
+	Tool string
+	// Module path for go tools, download URL template for binaries.
+	Source  string
+	Version string
+	// Module sum (h1:...) for go tools,
+	// checksums of the download per platform for binaries, e.g. "linux/amd64=sha256:...,darwin/arm64=sha256:...".
+	Sum string
+}
+
+func (e lockEntry) String() string {
+	return strings.Join([]string{e.Tool, e.Source, e.Version, e.Sum}, " ")
+}
+
+// Returns the path of the lockfile in the project root.
+func lockfilePath() (string, error) {
+	root, err := cache.ProjectRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, LockfileName), nil
+}
+
+func readLockfile(file string) (map[string]lockEntry, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading lockfile: %w", err)
+	}
+	entries := map[string]lockEntry{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("%s:%d: expected 4 fields, got %d", file, line, len(fields))
+		}
+		entries[fields[0]] = lockEntry{Tool: fields[0], Source: fields[1], Version: fields[2], Sum: fields[3]}
+	}
+	return entries, scanner.Err()
+}
+
+func writeLockfile(file string, entries []lockEntry) error {
+	slices.SortFunc(entries, func(a, b lockEntry) int { return strings.Compare(a.Tool, b.Tool) })
+	var b strings.Builder
+	b.WriteString(lockfileHeader)
+	for _, e := range entries {
+		b.WriteString(e.String() + "\n")
+	}
+	return writeFileAtomic(file, []byte(b.String()), 0o644)
+}
+
+// Computes lockfile entries for the given or all installed tools.
+func (d *dependencyManager) lockEntries(tools ...string) ([]lockEntry, error) {
+	if len(tools) == 0 {
+		tools = slices.Collect(maps.Keys(d.deps))
+	}
+	entries := make([]lockEntry, 0, len(tools))
+	for _, tool := range tools {
+		e, err := d.lockEntry(tool, d.deps[tool])
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func (d *dependencyManager) lockEntry(tool, source string) (lockEntry, error) {
+	// Binaries are locked by their definition, so the lockfile is the same on every platform.
+	// Downloads are verified against these checksums when installed.
+	if bin, downloaded := d.downloads[tool]; downloaded {
+		// Fields are separated by spaces, which templates may contain.
+		url := strings.ReplaceAll(bin.URL, " ", "%20")
+		return lockEntry{Tool: tool, Source: url, Version: d.versions[tool], Sum: platformSums(bin.Checksums)}, nil
+	}
+
+	bin := filepath.Join(d.Bin(), tool)
+	bi, err := buildinfo.ReadFile(bin)
+	if err != nil {
+		return lockEntry{}, fmt.Errorf("reading build info of %s: %w", tool, err)
+	}
+	if len(bi.Main.Sum) > 0 {
+		return lockEntry{Tool: tool, Source: bi.Main.Path, Version: bi.Main.Version, Sum: bi.Main.Sum}, nil
+	}
+	// Tools built within a go.mod are provided by a dependency of the main module.
+	if m := providingDep(bi.Deps, bi.Path); m != nil && len(m.Sum) > 0 {
+		return lockEntry{Tool: tool, Source: m.Path, Version: m.Version, Sum: m.Sum}, nil
+	}
+	// Tools built from a local module carry no module sum.
+	source, _, _ = strings.Cut(source, "@")
+	sum, err := fileSHA256(bin)
+	if err != nil {
+		return lockEntry{}, err
+	}
+	return lockEntry{Tool: tool, Source: source, Version: d.versions[tool], Sum: "sha256:" + sum}, nil
+}
+
+// Returns the module of deps containing pkg, preferring the longest module path.
+func providingDep(deps []*debug.Module, pkg string) *debug.Module {
+	var match *debug.Module
+	for _, m := range deps {
+		if pkg != m.Path && !strings.HasPrefix(pkg, m.Path+"/") {
+			continue
+		}
+		if match == nil || len(m.Path) > len(match.Path) {
+			match = m
+		}
+	}
+	return match
+}
+
+// Joins checksums keyed by platform in a stable order.
+func platformSums(checksums map[string]string) string {
+	if len(checksums) == 0 {
+		return "-"
+	}
+	sums := make([]string, 0, len(checksums))
+	for _, platform := range slices.Sorted(maps.Keys(checksums)) {
+		sums = append(sums, platform+"=sha256:"+strings.ToLower(checksums[platform]))
+	}
+	return strings.Join(sums, ",")
+}
+
+// Checks that the given or all installed tools match the lockfile.
+func (d *dependencyManager) verifyLockfile(file string, tools ...string) error {
+	locked, err := readLockfile(file)
+	if err != nil {
+		return err
+	}
+	installed, err := d.lockEntries(tools...)
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(installed, func(a, b lockEntry) int { return strings.Compare(a.Tool, b.Tool) })
+
+	var errs []error
+	for _, e := range installed {
+		l, ok := locked[e.Tool]
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("%s: missing from lockfile", e.Tool))
+		case l != e:
+			errs = append(errs, fmt.Errorf("%s: lockfile has %q, installed %q", e.Tool, l.String(), e.String()))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("tools do not match %s: %w", file, errors.Join(errs...))
+	}
+	return nil
+}
+
+// Verifies the installed tool against the project lockfile, if enabled.
+// Tools are not installed in dry run mode.
+func (d *dependencyManager) verifyLocked(tool string) error {
+	if !d.verifyLock || d.dryRun {
+		return nil
+	}
+	file, err := lockfilePath()
+	if err != nil {
+		return err
+	}
+	return d.verifyLockfile(file, tool)
+}
+
+func fileSHA256(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hashing %s: %w", file, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,53 @@
+package run
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDependencyManager_lockfile(t *testing.T) {
+	t.Parallel()
+
+	bin := Binary{
+		Tool: "tool", Version: "1.0.0", URL: "https://example.com/{{ .Version }}/{{.OS}}/{{.Arch}}/tool",
+		Checksums: map[string]string{"linux/amd64": "AA11", "darwin/arm64": "bb22"},
+	}
+	dm, err := newDependencyManager(newDependencyRun(), t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, dm.RegisterBinary(t.Context(), bin))
+
+	lockfile := filepath.Join(t.TempDir(), LockfileName)
+	entries, err := dm.lockEntries()
+	require.NoError(t, err)
+	require.NoError(t, writeLockfile(lockfile, entries))
+
+	data, err := os.ReadFile(lockfile)
+	require.NoError(t, err)
+	assert.Equal(t, lockfileHeader+
+		"tool https://example.com/{{%20.Version%20}}/{{.OS}}/{{.Arch}}/tool v1.0.0 "+
+		"darwin/arm64=sha256:bb22,linux/amd64=sha256:aa11\n", string(data),
+		"independent of the current platform")
+	require.NoError(t, dm.verifyLockfile(lockfile))
+
+	bin.Checksums = map[string]string{"linux/amd64": "cc33"}
+	changed, err := newDependencyManager(newDependencyRun(), t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, changed.RegisterBinary(t.Context(), bin))
+	require.ErrorContains(t, changed.verifyLockfile(lockfile), "tool: lockfile has")
+
+	require.NoError(t, writeLockfile(lockfile, nil))
+	require.ErrorContains(t, dm.verifyLockfile(lockfile), "tool: missing from lockfile")
+}
+
+func Test_readLockfile_invalid(t *testing.T) {
+	t.Parallel()
+
+	lockfile := filepath.Join(t.TempDir(), LockfileName)
+	require.NoError(t, os.WriteFile(lockfile, []byte(lockfileHeader+"tool v1.0.0\n"), 0o644))
+	_, err := readLockfile(lockfile)
+	require.EqualError(t, err, lockfile+":2: expected 4 fields, got 2")
+}
@@ -3,6 +3,7 @@ package run
 import (
 	"context"
 	"embed"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"go/ast"
@@ -20,6 +21,9 @@ import (
 	"text/tabwriter"
 
 	"github.com/mattn/go-isatty"
+
+	"pkg.package-operator.run/cardboard/cache"
+	"pkg.package-operator.run/cardboard/sh"
 )
 
 type ManagerOption interface {
@@ -58,6 +62,15 @@ func (s WithSources) ApplyToManager(m *Manager) {
 	m.sources = embed.FS(s)
 }
 
+// Install registered tools when a target first needs them instead of before every target.
+// Tools are needed when declared as dependency via Manager.Tool or executed through sh.Runner.
+// Scripts run via sh.Runner.Bash install the tools whose names appear in the script.
+type WithLazyToolInstall struct{}
+
+func (WithLazyToolInstall) ApplyToManager(m *Manager) {
+	m.lazyTools = true
+}
+
 type WithStdout struct{ io.Writer }
 
 func (stdout WithStdout) ApplyToManager(m *Manager) {
@@ -70,6 +83,8 @@ func (stderr WithStderr) ApplyToManager(m *Manager) {
 	m.stderr = stderr.Writer
 }
 
+// Whether colors are disabled via NO_COLOR or the terminal does not support them.
+// Disables colors regardless of Config.NoColor.
 var NoColor = os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" ||
 	(!isatty.IsTerminal(os.Stdout.Fd()) && !isatty.IsCygwinTerminal(os.Stdout.Fd()))
 
@@ -81,12 +96,22 @@ type Manager struct {
 	runOnce        sync.Once
 	dr             *dependencyRun
 	dm             *dependencyManager
+	background     backgroundProcesses
+	recorder       *sh.Recorder
 	stdout, stderr io.Writer
 
 	// config
-	sources  embed.FS
-	parallel []Dependency
-	serial   []Dependency
+	sources    embed.FS
+	parallel   []Dependency
+	serial     []Dependency
+	config     Config
+	configFile string
+	lazyTools  bool
+	// target ID -> globs of files to watch.
+	watchInputs map[string][]string
+	// effective config after applying config file and environment.
+	rc        *resolvedConfig
+	configErr error
 }
 
 type target struct {
@@ -100,23 +125,63 @@ func New(opts ...ManagerOption) *Manager {
 	m := &Manager{
 		targets: map[string]target{},
 		dr:      dr,
-		dm:      newDependencyManager(dr),
+		config:  defaultConfig(),
 	}
 	for _, opt := range opts {
 		opt.ApplyToManager(m)
 	}
-	if m.logger == nil {
-		m.logger = slog.Default()
-	}
 	if m.stderr == nil {
 		m.stderr = os.Stderr
 	}
 	if m.stdout == nil {
 		m.stdout = os.Stdout
 	}
+
+	m.rc, m.configErr = m.resolveConfig()
+	if m.configErr != nil {
+		// Continue with options only, the error is reported when running.
+		m.rc = &resolvedConfig{Config: m.config}
+	}
+	if m.logger == nil {
+		m.logger = slog.Default()
+		if m.rc.isSet("logLevel") {
+			m.logger = slog.New(slog.NewTextHandler(m.stderr, &slog.HandlerOptions{Level: m.rc.LogLevel}))
+		}
+	}
+	m.setupRecorder()
+	dm, err := newDependencyManager(dr, m.rc.CacheDir)
+	if err != nil {
+		// Continue with the unresolved cache directory, the error is reported when running.
+		m.configErr = errors.Join(m.configErr, err)
+		dm = dependencyManagerAt(dr, m.rc.CacheDir, m.rc.CacheDir)
+	}
+	m.dm = dm
+	m.dm.logger = m.logger
+	m.dm.offline = m.rc.Offline
+	m.dm.vendorDir = m.rc.ToolsVendorDir
+	m.dm.dryRun = m.rc.DryRun
+	m.dm.verifyLock = m.rc.Lockfile == LockfileModeVerify
+	dr.parallelism = m.rc.Parallelism
+	dr.noColor = m.rc.NoColor || NoColor
+	dr.output = &outputConfig{
+		mode:    m.rc.OutputMode,
+		markers: m.rc.GroupMarkers,
+		out:     m.stdout,
+	}
 	return m
 }
 
+func (m *Manager) resolveConfig() (*resolvedConfig, error) {
+	configFile := m.configFile
+	if len(configFile) == 0 {
+		var err error
+		if configFile, err = findConfigFile(); err != nil {
+			return nil, fmt.Errorf("looking up config file: %w", err)
+		}
+	}
+	return resolveConfig(m.config, configFile, os.LookupEnv)
+}
+
 // Executes dependencies one after the other.
 func (m *Manager) SerialDeps(ctx context.Context, parent DependencyIDer, deps ...Dependency) error {
 	return m.dr.Serial(ctx, parent, deps...)
@@ -138,6 +203,10 @@ func (m *Manager) Call(ctx context.Context, id string, args []string) (err error
 	if !ok {
 		return &UnknownTargetError{ID: id}
 	}
+	if m.lazyTools {
+		ctx = sh.ContextWithCommandHook(ctx, m.dm.ensureTool)
+	}
+	ctx = m.contextWithRunner(ctx)
 	return m.dr.Serial(ctx, DependencyID("."), FnWithName(target.idWithArgs(args), func() error {
 		return target.run(ctx, args)
 	}))
@@ -146,20 +215,72 @@ func (m *Manager) Call(ctx context.Context, id string, args []string) (err error
 func (m *Manager) Run(ctx context.Context) error {
 	var err error
 	m.runOnce.Do(func() {
+		if m.configErr != nil {
+			err = m.configErr
+			return
+		}
+
 		// Make sure deps are in the path for everything we run.
 		os.Setenv("PATH", m.dm.Bin()+":"+os.Getenv("PATH"))
+		// Make the cache directory available to modules.
+		cacheDir, cacheErr := cache.Resolve(m.rc.CacheDir)
+		if cacheErr != nil {
+			err = fmt.Errorf("resolving cache directory: %w", cacheErr)
+			return
+		}
+		os.Setenv(cache.DirEnv, cacheDir)
+		// Make the container runtime setting available to modules.
+		if len(m.rc.ContainerRuntime) > 0 {
+			os.Setenv("CARDBOARD_CONTAINER_RUNTIME", m.rc.ContainerRuntime)
+		}
 
+		closeTrace, traceErr := m.openTrace()
+		if traceErr != nil {
+			err = traceErr
+			return
+		}
 		err = m.run(ctx)
+		err = errors.Join(err, closeTrace())
 	})
 	return err
 }
 
+// Configures sh.Runners used by targets to hand background processes to the Manager
+// and to use the Managers recorder.
+func (m *Manager) contextWithRunner(ctx context.Context) context.Context {
+	ctx = sh.ContextWithLifecycle(ctx, &m.background)
+	if m.rc.NoLogPrefix {
+		ctx = sh.ContextWithoutLogPrefix(ctx)
+	}
+	if m.recorder != nil {
+		ctx = sh.ContextWithRecorder(ctx, m.recorder)
+	}
+	return ctx
+}
+
 // Register a go tool to be installed.
 // The manager ensures that the tool is go install'ed project local and available in $PATH.
 func (m *Manager) RegisterGoTool(ctx context.Context, tool, packageURL, version string) error {
 	return m.dm.Register(ctx, tool, packageURL, version)
 }
 
+// Returns a dependency installing the given registered tool.
+func (m *Manager) Tool(tool string) Dependency {
+	return m.dm.Tool(tool)
+}
+
+// Register all tools declared via tool directives in the given go.mod file to be installed.
+// Tools are built within the module of the go.mod file, using its versions and go.sum.
+func (m *Manager) RegisterGoModTools(ctx context.Context, goModFile string) error {
+	return m.dm.RegisterGoModTools(ctx, goModFile)
+}
+
+// Register a prebuilt binary to be downloaded.
+// The manager ensures that the binary is downloaded project local, verified and available in $PATH.
+func (m *Manager) RegisterBinary(ctx context.Context, bin Binary) error {
+	return m.dm.RegisterBinary(ctx, bin)
+}
+
 func (m *Manager) RegisterAndRun(ctx context.Context, targetGroups ...any) error {
 	if err := m.registerAll(targetGroups...); err != nil {
 		return decorateWithCallingSourceLine(err)
@@ -240,9 +361,37 @@ func (m *Manager) run(ctx context.Context) error {
 	if len(args) < 2 || args[1] == "help" {
 		return m.printHelp()
 	}
+	switch {
+	case args[1] == "config":
+		return m.rc.print(m.stdout)
+	case args[1] == "cache" && len(args) > 2 && args[2] == "clean":
+		return cache.Clean(args[3:]...)
+	case args[1] == "tools" && len(args) == 2:
+		return m.dm.printTools(m.stdout)
+	case args[1] == "tools" && len(args) > 2 && args[2] == "lock":
+		return m.lockTools(ctx)
+	case args[1] == "--watch" && len(args) > 2:
+		return m.watch(ctx, args[2], args[3:])
+	}
 
-	// Always do binary dependencies first.
-	if !m.dm.IsEmpty() {
+	// Execute actual target.
+	err := m.runTarget(ctx, args[1], args[2:])
+	if reportErr := m.printReport(); reportErr != nil {
+		return errors.Join(err, reportErr)
+	}
+	return err
+}
+
+// Installs tools, runs all manager dependencies and then the target itself.
+// Background processes started by the target are stopped afterwards.
+func (m *Manager) runTarget(ctx context.Context, id string, args []string) (err error) {
+	ctx = m.contextWithRunner(ctx)
+	defer func() {
+		err = errors.Join(err, m.background.stopAll())
+	}()
+
+	// Always do binary dependencies first, unless installed when needed.
+	if !m.dm.IsEmpty() && !m.lazyTools {
 		if err := m.dr.Serial(ctx, DependencyID("."), m.dm); err != nil {
 			return err
 		}
@@ -257,10 +406,55 @@ func (m *Manager) run(ctx context.Context) error {
 		return fmt.Errorf("serial dependency failed: %w", err)
 	}
 
-	// Execute actual target.
-	err := m.Call(ctx, args[1], args[2:])
-	fmt.Fprint(m.stderr, m.dr.Report())
-	return err
+	return m.Call(ctx, id, args)
+}
+
+// Installs all registered tools and records them in the lockfile.
+func (m *Manager) lockTools(ctx context.Context) error {
+	// The lockfile is about to be replaced.
+	m.dm.verifyLock = false
+	if err := m.dr.Serial(ctx, DependencyID("."), m.dm); err != nil {
+		return err
+	}
+	entries, err := m.dm.lockEntries()
+	if err != nil {
+		return err
+	}
+	lockfile, err := lockfilePath()
+	if err != nil {
+		return err
+	}
+	if err := writeLockfile(lockfile, entries); err != nil {
+		return fmt.Errorf("writing lockfile: %w", err)
+	}
+	fmt.Fprintf(m.stdout, "Locked %d tools in %s\n", len(entries), lockfile)
+	return nil
+}
+
+func (m *Manager) printReport() error {
+	switch m.rc.ReportFormat {
+	case ReportFormatNone:
+		return nil
+	case ReportFormatJSON:
+		deps, err := m.dr.ReportJSON()
+		if err != nil {
+			return err
+		}
+		report, err := json.MarshalIndent(struct {
+			Dependencies json.RawMessage    `json:"dependencies"`
+			Background   []backgroundReport `json:"background,omitempty"`
+		}{deps, m.background.reports()}, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(m.stderr, string(report))
+		return err
+	default:
+		if _, err := fmt.Fprint(m.stderr, m.dr.Report()); err != nil {
+			return err
+		}
+		return m.background.report(m.stderr)
+	}
 }
 
 func (m *Manager) registerAll(targetGroups ...any) error {
@@ -122,8 +122,8 @@ func TestManager_Run(t *testing.T) {
 	// strip [took x] from output, because it is not stable.
 	tookRegEx := regexp.MustCompile(`(?m) \[took .*\]`)
 	assert.Equal(t, `Cardboard Report:
-[OK] pkg.package-operator.run/cardboard/run.MyThing{field:hans}.TestWithDep([]string{})
-└── [OK] pkg.package-operator.run/cardboard/run.MyThing{field:hans}.private("banana")
+[OK] run.MyThing{field:hans}.TestWithDep([]string{})
+└── [OK] run.MyThing{field:hans}.private("banana")
 `, string(tookRegEx.ReplaceAll(stderrBuf.Bytes(), nil)))
 }
 
@@ -146,8 +146,8 @@ func TestManager_Run_Error(t *testing.T) {
 	// strip [took x] from output, because it is not stable.
 	tookRegEx := regexp.MustCompile(`(?m) \[took .*\]`)
 	assert.Equal(t, `Cardboard Report:
-[ERR] pkg.package-operator.run/cardboard/run.MyThing{field:hans}.TestWithDepErr([]string{})
-└── [ERR] pkg.package-operator.run/cardboard/run.MyThing{field:hans}.privateErr("banana")
+[ERR] run.MyThing{field:hans}.TestWithDepErr([]string{})
+└── [ERR] run.MyThing{field:hans}.privateErr("banana")
     explosion
 `, string(tookRegEx.ReplaceAll(stderrBuf.Bytes(), nil)))
 }
@@ -171,8 +171,8 @@ func TestManager_Run_MustPanic(t *testing.T) {
 	// strip [took x] from output, because it is not stable.
 	tookRegEx := regexp.MustCompile(`(?m) \[took .*\]`)
 	assert.Equal(t, `Cardboard Report:
-[ERR] pkg.package-operator.run/cardboard/run.MyThing{field:hans}.TestWithDepMustPanic([]string{})
-└── [ERR] pkg.package-operator.run/cardboard/run.MyThing{field:hans}.privateMustPanic("banana")
+[ERR] run.MyThing{field:hans}.TestWithDepMustPanic([]string{})
+└── [ERR] run.MyThing{field:hans}.privateMustPanic("banana")
     explosion
 `, string(tookRegEx.ReplaceAll(stderrBuf.Bytes(), nil)))
 }
@@ -0,0 +1,162 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+
+	"pkg.package-operator.run/cardboard/cache"
+)
+
+// Returns the GOPROXY-layout directory go tools are installed from in offline mode.
+// This is the vendor directory if configured, the download cache of GOMODCACHE otherwise.
+func (d *dependencyManager) offlineSource(ctx context.Context) (string, error) {
+	if len(d.vendorDir) > 0 {
+		return cache.Resolve(d.vendorDir)
+	}
+	modCache, err := d.runner.Output(ctx, "go", "env", "GOMODCACHE")
+	if err != nil {
+		return "", fmt.Errorf("looking up GOMODCACHE: %w", err)
+	}
+	return filepath.Join(modCache, "cache", "download"), nil
+}
+
+// Environment for go install to only use the given offline source.
+// The download cache of GOMODCACHE has GOPROXY layout as well,
+// but is only consulted with an explicit file:// proxy, GOPROXY=off disables module lookups altogether.
+// Modules are still verified against the checksum database,
+// which works offline for modules it was consulted for before, e.g. when filling the module cache.
+// It is only skipped, if installed tools are verified against the lockfile instead.
+func offlineEnv(source string, verifyLock bool) map[string]string {
+	env := map[string]string{
+		"GOPROXY": "file://" + filepath.ToSlash(source) + ",off",
+	}
+	if verifyLock {
+		env["GOSUMDB"] = "off"
+	}
+	return env
+}
+
+// Checks whether the module providing packageURL at the given version is available in the GOPROXY-layout source.
+// Module lookups need the version in @v/list, its zip and go.mod.
+// The module path is unknown, so every parent path of the package is tried.
+// The check is best-effort: only go.mod files of direct requirements are checked,
+// so go may still fail to install the tool, if other modules of the build list are missing.
+func moduleAvailable(source, packageURL, version string) bool {
+	for modPath := packageURL; modPath != "." && modPath != "/"; modPath = path.Dir(modPath) {
+		if versionListed(source, modPath, "v"+version) {
+			return requirementsAvailable(source, modPath, "v"+version)
+		}
+	}
+	return false
+}
+
+// Returns the path of the module version in the GOPROXY-layout source without file extension.
+func versionPath(source, modPath, version string) (string, bool) {
+	escPath, err := module.EscapePath(modPath)
+	if err != nil {
+		return "", false
+	}
+	escVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return "", false
+	}
+	return filepath.Join(source, escPath, "@v", escVersion), true
+}
+
+// Reports whether the module version is listed and its zip is present in the source.
+func versionListed(source, modPath, version string) bool {
+	p, ok := versionPath(source, modPath, version)
+	if !ok {
+		return false
+	}
+	if _, err := os.Stat(p + ".zip"); err != nil {
+		return false
+	}
+	list, err := os.ReadFile(filepath.Join(filepath.Dir(p), "list"))
+	if err != nil {
+		return false
+	}
+	return slices.Contains(strings.Fields(string(list)), version)
+}
+
+// Reports whether the go.mod files of the module version and its direct requirements are present in the source.
+func requirementsAvailable(source, modPath, version string) bool {
+	p, ok := versionPath(source, modPath, version)
+	if !ok {
+		return false
+	}
+	data, err := os.ReadFile(p + ".mod")
+	if err != nil {
+		return false
+	}
+	f, err := modfile.ParseLax(p+".mod", data, nil)
+	if err != nil {
+		return false
+	}
+	for _, req := range f.Require {
+		p, ok := versionPath(source, req.Mod.Path, req.Mod.Version)
+		if !ok {
+			return false
+		}
+		if _, err := os.Stat(p + ".mod"); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns tools that would have to be installed but are not available offline.
+func (d *dependencyManager) missingOffline(ctx context.Context, tools ...string) ([]string, string, error) {
+	source, err := d.offlineSource(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var missing []string
+	for _, tool := range tools {
+		version := strings.TrimPrefix(d.versions[tool], "v")
+		if _, ok := d.downloads[tool]; ok {
+			reason, err := d.downloadReason(tool, version)
+			if err != nil {
+				return nil, "", err
+			}
+			if len(reason) > 0 {
+				missing = append(missing, d.deps[tool])
+			}
+			continue
+		}
+
+		packageURL, _, _ := strings.Cut(d.deps[tool], "@")
+		if len(d.goToolReason(tool)) > 0 &&
+			!moduleAvailable(source, packageURL, version) {
+			missing = append(missing, d.deps[tool])
+		}
+	}
+	return missing, source, nil
+}
+
+// Fails listing all tools that cannot be installed offline.
+func (d *dependencyManager) checkOffline(ctx context.Context, tools ...string) error {
+	missing, source, err := d.missingOffline(ctx, tools...)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return &MissingToolsError{Source: source, Tools: missing}
+	}
+	return nil
+}
+
+// Checks all registered tools are available, before any of them is installed.
+func (d *dependencyManager) checkOfflineAll(ctx context.Context) error {
+	return d.checkOffline(ctx, slices.Sorted(maps.Keys(d.deps))...)
+}
@@ -0,0 +1,87 @@
+package run
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Adds the module version to the GOPROXY-layout source.
+func writeProxyModule(t *testing.T, source, escPath, version, goMod string) {
+	t.Helper()
+	modDir := filepath.Join(source, filepath.FromSlash(escPath), "@v")
+	require.NoError(t, os.MkdirAll(modDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(modDir, version+".zip"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(modDir, version+".mod"), []byte(goMod), 0o644))
+	list, _ := os.ReadFile(filepath.Join(modDir, "list"))
+	require.NoError(t, os.WriteFile(filepath.Join(modDir, "list"), append(list, version+"\n"...), 0o644))
+}
+
+func Test_moduleAvailable(t *testing.T) {
+	t.Parallel()
+
+	source := t.TempDir()
+	writeProxyModule(t, source, "github.com/!example/tools", "v1.2.3", "module github.com/Example/tools\n")
+	writeProxyModule(t, source, "github.com/!example/tools", "v1.2.4", "module github.com/Example/tools\n")
+	require.NoError(t, os.WriteFile(filepath.Join(source, "github.com", "!example", "tools", "@v", "list"),
+		[]byte("v1.2.3\n"), 0o644))
+
+	assert.True(t, moduleAvailable(source, "github.com/Example/tools/cmd/lint", "1.2.3"))
+	assert.True(t, moduleAvailable(source, "github.com/Example/tools", "1.2.3"))
+	assert.False(t, moduleAvailable(source, "github.com/Example/tools/cmd/lint", "1.2.4"), "not listed")
+	assert.False(t, moduleAvailable(source, "github.com/Example/tools/cmd/lint", "1.2.5"))
+	assert.False(t, moduleAvailable(source, "github.com/example/tools/cmd/lint", "1.2.3"))
+}
+
+func Test_moduleAvailable_requirements(t *testing.T) {
+	t.Parallel()
+
+	source := t.TempDir()
+	writeProxyModule(t, source, "example.com/dep", "v0.1.0", "module example.com/dep\n")
+	writeProxyModule(t, source, "example.com/tools", "v1.0.0",
+		"module example.com/tools\n\nrequire example.com/dep v0.1.0\n")
+	writeProxyModule(t, source, "example.com/tools", "v1.1.0",
+		"module example.com/tools\n\nrequire (\n\texample.com/dep v0.1.0\n\texample.com/gone v0.2.0\n)\n")
+
+	assert.True(t, moduleAvailable(source, "example.com/tools/cmd/lint", "1.0.0"))
+	assert.False(t, moduleAvailable(source, "example.com/tools/cmd/lint", "1.1.0"), "requirement missing")
+}
+
+func TestDependencyManager_checkOffline(t *testing.T) {
+	t.Parallel()
+
+	source := t.TempDir()
+	writeProxyModule(t, source, "example.com/tools", "v1.0.0", "module example.com/tools\n")
+
+	dm, err := newDependencyManager(newDependencyRun(), t.TempDir())
+	require.NoError(t, err)
+	dm.offline = true
+	dm.vendorDir = source
+	require.NoError(t, dm.Register(t.Context(), "lint", "example.com/tools/cmd/lint", "1.0.0"))
+	require.NoError(t, dm.Register(t.Context(), "gen", "example.com/other/cmd/gen", "2.0.0"))
+	require.NoError(t, dm.RegisterBinary(t.Context(), Binary{
+		Tool: "kubectl", Version: "1.30.0", URL: "https://example.com/{{.Version}}/kubectl",
+	}))
+
+	err = dm.Run(t.Context())
+	require.EqualError(t, err, "offline mode: tools not available in "+source+": "+
+		"example.com/other/cmd/gen@v2.0.0, https://example.com/1.30.0/kubectl")
+	var mte *MissingToolsError
+	require.ErrorAs(t, err, &mte)
+	assert.Len(t, mte.Tools, 2)
+}
+
+func Test_offlineEnv(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, map[string]string{
+		"GOPROXY": "file:///go/pkg/mod/cache/download,off",
+	}, offlineEnv("/go/pkg/mod/cache/download", false), "checksum database stays enabled")
+	assert.Equal(t, map[string]string{
+		"GOPROXY": "file:///go/pkg/mod/cache/download,off",
+		"GOSUMDB": "off",
+	}, offlineEnv("/go/pkg/mod/cache/download", true), "lockfile verifies tools")
+}
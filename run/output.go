@@ -0,0 +1,153 @@
+package run
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"pkg.package-operator.run/cardboard/sh"
+)
+
+// OutputMode controls how output of parallel dependencies is written.
+type OutputMode string
+
+const (
+	// Output is streamed as it is produced.
+	// Output of dependencies running in parallel may interleave.
+	OutputModeStream OutputMode = "stream"
+	// Output of each parallel dependency is buffered
+	// and flushed as a single block when the dependency finishes.
+	OutputModeGrouped OutputMode = "grouped"
+	// Like OutputModeGrouped, but output is only flushed when the dependency failed.
+	OutputModeGroupedOnFailure OutputMode = "grouped-on-failure"
+)
+
+// GroupMarkers controls the markers written around grouped output blocks.
+type GroupMarkers string
+
+const (
+	// Detect CI system from environment variables.
+	GroupMarkersAuto GroupMarkers = "auto"
+	// No markers around output blocks.
+	GroupMarkersNone GroupMarkers = "none"
+	// GitHub Actions ::group:: workflow commands.
+	GroupMarkersGitHub GroupMarkers = "github"
+	// GitLab CI collapsible sections.
+	GroupMarkersGitLab GroupMarkers = "gitlab"
+)
+
+// Buffer output of parallel dependencies.
+type WithOutputMode OutputMode
+
+func (om WithOutputMode) ApplyToManager(m *Manager) {
+	m.config.OutputMode = OutputMode(om)
+}
+
+// Markers to wrap grouped output blocks in.
+// Only used together with a grouped OutputMode.
+type WithGroupMarkers GroupMarkers
+
+func (gm WithGroupMarkers) ApplyToManager(m *Manager) {
+	m.config.GroupMarkers = GroupMarkers(gm)
+}
+
+type outputConfig struct {
+	mode    OutputMode
+	markers GroupMarkers
+	// fallback writer if none is set in the context.
+	out io.Writer
+	// counter to generate unique section names.
+	sections atomic.Uint64
+}
+
+func detectGroupMarkers() GroupMarkers {
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		return GroupMarkersGitHub
+	case os.Getenv("GITLAB_CI") == "true":
+		return GroupMarkersGitLab
+	default:
+		return GroupMarkersNone
+	}
+}
+
+// Runs the given dependency, buffering and flushing its output according to the output mode.
+func (o *outputConfig) run(ctx context.Context, dep Dependency) error {
+	if o == nil || o.mode == "" || o.mode == OutputModeStream {
+		return dep.Run(ctx)
+	}
+
+	buf := &syncBuffer{}
+	err := dep.Run(sh.ContextWithOutput(ctx, buf))
+	if buf.Len() == 0 || (o.mode == OutputModeGroupedOnFailure && err == nil) {
+		return err
+	}
+
+	out, ok := sh.OutputFromContext(ctx)
+	if !ok {
+		out = o.out
+	}
+	if out == nil {
+		out = os.Stdout
+	}
+	o.flush(out, dep.ID(), buf.Bytes())
+	return err
+}
+
+// Writes a block of output wrapped in group markers.
+func (o *outputConfig) flush(out io.Writer, title string, data []byte) {
+	var block bytes.Buffer
+	if !bytes.HasSuffix(data, []byte{'\n'}) {
+		data = append(data, '\n')
+	}
+
+	markers := o.markers
+	if markers == "" || markers == GroupMarkersAuto {
+		markers = detectGroupMarkers()
+	}
+	switch markers {
+	case GroupMarkersGitHub:
+		fmt.Fprintf(&block, "::group::%s\n", title)
+		block.Write(data)
+		fmt.Fprintln(&block, "::endgroup::")
+	case GroupMarkersGitLab:
+		section := fmt.Sprintf("cardboard_%d", o.sections.Add(1))
+		fmt.Fprintf(&block, "\033[0Ksection_start:%d:%s[collapsed=true]\r\033[0K%s\n",
+			time.Now().Unix(), section, title)
+		block.Write(data)
+		fmt.Fprintf(&block, "\033[0Ksection_end:%d:%s\r\033[0K\n", time.Now().Unix(), section)
+	default:
+		block.Write(data)
+	}
+	// Write the whole block at once, so it does not interleave with other output.
+	_, _ = block.WriteTo(out)
+}
+
+// bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	buf bytes.Buffer
+	mux sync.Mutex
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Len() int {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.buf.Len()
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return bytes.Clone(b.buf.Bytes())
+}
@@ -0,0 +1,54 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pkg.package-operator.run/cardboard/sh"
+)
+
+func Test_outputConfig_grouped(t *testing.T) {
+	t.Parallel()
+
+	out := &syncBuffer{}
+	dr := newDependencyRun()
+	dr.output = &outputConfig{mode: OutputModeGrouped, markers: GroupMarkersGitHub, out: out}
+
+	ctx := t.Context()
+	err := dr.Parallel(ctx, DependencyID("_test"),
+		FnWithName("a", func(ctx context.Context) error {
+			return sh.New().Bash(ctx, "echo a1", "sleep 0.05", "echo a2")
+		}),
+		FnWithName("b", func(ctx context.Context) error {
+			return sh.New().Bash(ctx, "sleep 0.02", "echo b1", "sleep 0.05", "echo b2")
+		}),
+	)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(out.Bytes()), "::group::a\na1\na2\n::endgroup::\n")
+	assert.Contains(t, string(out.Bytes()), "::group::b\nb1\nb2\n::endgroup::\n")
+}
+
+func Test_outputConfig_groupedOnFailure(t *testing.T) {
+	t.Parallel()
+
+	out := &syncBuffer{}
+	dr := newDependencyRun()
+	dr.output = &outputConfig{mode: OutputModeGroupedOnFailure, markers: GroupMarkersNone, out: out}
+
+	ctx := t.Context()
+	err := dr.Parallel(ctx, DependencyID("_test"),
+		FnWithName("ok", func(ctx context.Context) error {
+			return sh.New().Bash(ctx, "echo ok")
+		}),
+		FnWithName("fail", func(ctx context.Context) error {
+			return errors.Join(sh.New().Bash(ctx, "echo failed"), errTest)
+		}),
+	)
+	require.Error(t, err)
+	assert.Equal(t, "failed\n", string(out.Bytes()))
+}
@@ -0,0 +1,68 @@
+package run
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"pkg.package-operator.run/cardboard/cache"
+	"pkg.package-operator.run/cardboard/sh"
+)
+
+// Records commands executed via sh.Runner while targets run, see sh.Recorder.
+// Allows unit tests of targets to inspect commands and fake their results.
+type WithRecorder struct{ *sh.Recorder }
+
+func (r WithRecorder) ApplyToManager(m *Manager) {
+	m.recorder = r.Recorder
+	m.config.DryRun = r.DryRun
+}
+
+// Record commands executed via sh.Runner to the given file as JSON lines.
+// Relative paths are resolved against the project root.
+type WithTrace string
+
+func (t WithTrace) ApplyToManager(m *Manager) {
+	m.config.Trace = string(t)
+}
+
+// Record commands executed via sh.Runner without executing them.
+// Commands succeed without output, unless configured otherwise via WithRecorder.
+type WithDryRun struct{}
+
+func (WithDryRun) ApplyToManager(m *Manager) {
+	m.config.DryRun = true
+}
+
+// Sets up the recorder according to the effective config.
+func (m *Manager) setupRecorder() {
+	if m.recorder == nil && !m.rc.DryRun && len(m.rc.Trace) == 0 {
+		return
+	}
+	if m.recorder == nil {
+		m.recorder = &sh.Recorder{}
+	}
+	m.recorder.DryRun = m.rc.DryRun
+}
+
+// Opens the trace file, if configured.
+// The returned function closes it.
+func (m *Manager) openTrace() (func() error, error) {
+	if len(m.rc.Trace) == 0 {
+		return func() error { return nil }, nil
+	}
+	path, err := cache.Resolve(m.rc.Trace)
+	if err != nil {
+		return nil, fmt.Errorf("resolving trace file: %w", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("creating trace file: %w", err)
+	}
+	if m.recorder.Trace != nil {
+		m.recorder.Trace = io.MultiWriter(m.recorder.Trace, f)
+	} else {
+		m.recorder.Trace = f
+	}
+	return f.Close, nil
+}
@@ -0,0 +1,69 @@
+package run
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pkg.package-operator.run/cardboard/cache"
+	"pkg.package-operator.run/cardboard/sh"
+)
+
+type RecorderThing struct{}
+
+func (*RecorderThing) Release(ctx context.Context, _ []string) error {
+	r := sh.New(sh.WithCombinedOutput{Writer: io.Discard})
+	version, err := r.Output(ctx, "git", "describe", "--tags")
+	if err != nil {
+		return err
+	}
+	return r.Run(ctx, "git", "push", "origin", version)
+}
+
+func TestManager_Call_recorder(t *testing.T) {
+	t.Parallel()
+	rec := &sh.Recorder{
+		DryRun: true,
+		Results: []sh.FakeResult{
+			{Pattern: regexp.MustCompile(`^git describe`), Stdout: "v1.2.3\n"},
+		},
+	}
+	m := New(WithRecorder{rec}, WithStderr{io.Discard}, WithStdout{io.Discard})
+	require.NoError(t, m.Register(&RecorderThing{}))
+
+	require.NoError(t, m.Call(t.Context(), "RecorderThing:Release", nil))
+	cmds := rec.Commands()
+	require.Len(t, cmds, 2)
+	assert.Equal(t, "git describe --tags", cmds[0].String())
+	assert.Equal(t, "git push origin v1.2.3", cmds[1].String())
+	assert.True(t, cmds[1].DryRun)
+}
+
+//nolint:paralleltest // modifies os.Args.
+func TestManager_Run_trace(t *testing.T) {
+	// Run exports the cache directory, restore it afterwards.
+	t.Setenv(cache.DirEnv, t.TempDir())
+	trace := filepath.Join(t.TempDir(), "trace.jsonl")
+	m := New(WithTrace(trace), WithDryRun{}, WithStderr{io.Discard}, WithStdout{io.Discard})
+	require.NoError(t, m.Register(&RecorderThing{}))
+
+	os.Args = []string{"", "RecorderThing:Release"}
+	require.NoError(t, m.Run(t.Context()))
+
+	b, err := os.ReadFile(trace)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	require.Len(t, lines, 2)
+	var pushed sh.RecordedCommand
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &pushed))
+	assert.Equal(t, "git push origin", pushed.String(), "dry run commands succeed without output")
+	assert.True(t, pushed.DryRun)
+}
@@ -0,0 +1,131 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// A Dependency producing a result value that can be retrieved by dependents via Result.
+type ResultDependency[T any] interface {
+	Dependency
+	// Executes the dependency and returns its result.
+	RunResult(ctx context.Context) (T, error)
+}
+
+// Executes the given dependency, if it has not run already, and returns its memoized result.
+// Example:
+// digest, err := run.Result[string](ctx, mgr, buildDep).
+func Result[T any](ctx context.Context, mgr *Manager, dep ResultDependency[T]) (T, error) {
+	return resultFromRun(ctx, mgr.dr, dep)
+}
+
+func resultFromRun[T any](ctx context.Context, r *dependencyRun, dep ResultDependency[T]) (T, error) {
+	var zero T
+	caller := callerID(ctx, DependencyID("."))
+	localDep, err := r.get(ctx, dep, caller)
+	if err != nil {
+		return zero, fmt.Errorf("running %s: %w", dep.ID(), err)
+	}
+	defer r.release(caller, []Dependency{localDep})
+
+	if err := localDep.Run(ctx); err != nil {
+		return zero, fmt.Errorf("running %s: %w", dep.ID(), err)
+	}
+
+	once := localDep.(*depOnce)
+	if !once.hasValue {
+		return zero, fmt.Errorf("dependency %s was executed without producing a result", dep.ID())
+	}
+	if once.value == nil {
+		return zero, nil
+	}
+	v, ok := once.value.(T)
+	if !ok {
+		return zero, fmt.Errorf("dependency %s produced result of type %T, expected %T", dep.ID(), once.value, zero)
+	}
+	return v, nil
+}
+
+// Implemented by dependencies whose result can be memoized by depOnce.
+type anyResultRunner interface {
+	runAny(ctx context.Context) (any, error)
+}
+
+// Runs dep and returns its result, if it implements ResultDependency for any result type.
+// ok is false for dependencies without result, they are run via Run instead.
+func runResult(ctx context.Context, dep Dependency) (value any, ok bool, err error) {
+	if rr, ok := dep.(anyResultRunner); ok {
+		value, err = rr.runAny(ctx)
+		return value, true, err
+	}
+
+	method := reflect.ValueOf(dep).MethodByName("RunResult")
+	if !method.IsValid() {
+		return nil, false, nil
+	}
+	mt := method.Type()
+	if mt.NumIn() != 1 || mt.In(0) != contextType || mt.NumOut() != 2 || mt.Out(1) != errorType {
+		return nil, false, nil
+	}
+	out := method.Call([]reflect.Value{reflect.ValueOf(ctx)})
+	if errV := out[1].Interface(); errV != nil {
+		return out[0].Interface(), true, errV.(error)
+	}
+	return out[0].Interface(), true, nil
+}
+
+type resultDep[T any] struct {
+	id  string
+	run func(ctx context.Context) (T, error)
+}
+
+var _ ResultDependency[string] = (*resultDep[string])(nil)
+
+func (d *resultDep[T]) ID() string {
+	return d.id
+}
+
+func (d *resultDep[T]) Run(ctx context.Context) error {
+	_, err := d.run(ctx)
+	return err
+}
+
+func (d *resultDep[T]) RunResult(ctx context.Context) (T, error) {
+	return d.run(ctx)
+}
+
+func (d *resultDep[T]) runAny(ctx context.Context) (any, error) {
+	return d.run(ctx)
+}
+
+type resultFn[T any] interface {
+	func() (T, error) | func(context.Context) (T, error)
+}
+
+// Wraps a struct method returning a result for the dependency handler.
+func ResultMeth[T any, F resultFn[T]](s any, fn F) ResultDependency[T] {
+	return ResultFnWithName[T](methID(s, fn), fn)
+}
+
+// Wraps a function returning a result for the dependency handler.
+func ResultFn[T any, F resultFn[T]](fn F) ResultDependency[T] {
+	return ResultFnWithName[T](funcID(fn), fn)
+}
+
+// Wraps a function returning a result with a specific name for the dependency handler.
+func ResultFnWithName[T any, F resultFn[T]](name string, fn F) ResultDependency[T] {
+	return &resultDep[T]{
+		id: name,
+		run: func(ctx context.Context) (T, error) {
+			switch v := any(fn).(type) {
+			case func() (T, error):
+				return v()
+			case func(context.Context) (T, error):
+				return v(ctx)
+			}
+			var zero T
+			return zero, nil
+		},
+	}
+}
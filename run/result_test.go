@@ -0,0 +1,67 @@
+package run
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResult(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	build := ResultFnWithName[string]("build", func(_ context.Context) (string, error) {
+		calls.Add(1)
+		return "sha256:1234", nil
+	})
+
+	mgr := New()
+	ctx := t.Context()
+
+	// Results are memoized when the dependency first ran as part of a normal dependency run.
+	require.NoError(t, mgr.ParallelDeps(ctx, DependencyID("_test"), build))
+	for range 2 {
+		digest, err := Result(ctx, mgr, build)
+		require.NoError(t, err)
+		assert.Equal(t, "sha256:1234", digest)
+	}
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestResult_error(t *testing.T) {
+	t.Parallel()
+
+	build := ResultFnWithName[int]("build", func() (int, error) {
+		return 0, errTest
+	})
+
+	_, err := Result(t.Context(), New(), build)
+	require.EqualError(t, err, "running build: banana")
+}
+
+type myResultDep struct{ calls *atomic.Int32 }
+
+func (myResultDep) ID() string                    { return "myResultDep" }
+func (myResultDep) Run(ctx context.Context) error { return nil }
+
+func (d myResultDep) RunResult(_ context.Context) ([]string, error) {
+	d.calls.Add(1)
+	return []string{"a"}, nil
+}
+
+func TestResult_custom(t *testing.T) {
+	t.Parallel()
+
+	dep := myResultDep{calls: &atomic.Int32{}}
+	mgr := New()
+
+	// Results of custom dependencies are memoized as well, when they first ran as normal dependency.
+	require.NoError(t, mgr.SerialDeps(t.Context(), DependencyID("_test"), dep))
+	out, err := Result[[]string](t.Context(), mgr, dep)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, out)
+	assert.Equal(t, int32(1), dep.calls.Load())
+}
@@ -0,0 +1,395 @@
+package run
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"pkg.package-operator.run/cardboard/cache"
+	"pkg.package-operator.run/cardboard/sh"
+)
+
+// Snapshot of the files in a directory tree, used to detect changes made by generators or formatters.
+// In git repositories only tracked and untracked, not ignored files are considered.
+// The .git directory and the cardboard cache directory are always excluded.
+type Snapshot struct {
+	dir   string
+	files map[string]snapshotFile
+}
+
+// Files larger than this are only compared by checksum and not diffed.
+const maxDiffFileSize = 256 * 1024
+
+type snapshotFile struct {
+	sum [sha256.Size]byte
+	// content, nil for files larger than maxDiffFileSize.
+	data []byte
+}
+
+// TakeSnapshot records checksums of all regular files below dir and the content of small files.
+func TakeSnapshot(ctx context.Context, dir string) (*Snapshot, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	cacheDir, err := cache.Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := snapshotFiles(ctx, dir, cacheDir)
+	if err != nil {
+		return nil, fmt.Errorf("taking snapshot of %s: %w", dir, err)
+	}
+	s := &Snapshot{dir: dir, files: map[string]snapshotFile{}}
+	for _, name := range names {
+		f, err := readSnapshotFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if errors.Is(err, os.ErrNotExist) {
+			// Deleted, but still tracked by git.
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("taking snapshot of %s: %w", dir, err)
+		}
+		s.files[name] = f
+	}
+	return s, nil
+}
+
+// Lists files below dir as slash separated relative paths.
+// Asks git for tracked and untracked, not ignored files and walks the directory outside of git repositories.
+func snapshotFiles(ctx context.Context, dir, cacheDir string) ([]string, error) {
+	out, err := sh.New(sh.WithWorkDir(dir), sh.WithStderr{Writer: io.Discard}).
+		Output(ctx, "git", "ls-files", "-z", "--cached", "--others", "--exclude-standard")
+	if err == nil {
+		relCache, _ := filepath.Rel(dir, cacheDir)
+		relCache = filepath.ToSlash(relCache) + "/"
+		var names []string
+		for name := range strings.SplitSeq(out, "\x00") {
+			if len(name) == 0 || strings.HasPrefix(name, relCache) {
+				continue
+			}
+			names = append(names, name)
+		}
+		return names, nil
+	}
+
+	var names []string
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p == cacheDir || d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	return names, err
+}
+
+func readSnapshotFile(path string) (snapshotFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return snapshotFile{}, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return snapshotFile{}, err
+	}
+	if !info.Mode().IsRegular() {
+		return snapshotFile{}, os.ErrNotExist
+	}
+
+	var sf snapshotFile
+	if info.Size() <= maxDiffFileSize {
+		if sf.data, err = io.ReadAll(f); err != nil {
+			return snapshotFile{}, err
+		}
+		sf.sum = sha256.Sum256(sf.data)
+		return sf, nil
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return snapshotFile{}, err
+	}
+	copy(sf.sum[:], h.Sum(nil))
+	return sf, nil
+}
+
+// Check compares the current state of the directory with the snapshot.
+// Returns a *WorktreeChangedError if files were added, removed or modified.
+func (s *Snapshot) Check(ctx context.Context) error {
+	current, err := TakeSnapshot(ctx, s.dir)
+	if err != nil {
+		return err
+	}
+
+	e := &WorktreeChangedError{Dir: s.dir}
+	var diff strings.Builder
+	names := slices.Collect(maps.Keys(s.files))
+	for name := range current.files {
+		if _, ok := s.files[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		before, existed := s.files[name]
+		after, exists := current.files[name]
+		switch {
+		case !exists:
+			e.Removed = append(e.Removed, name)
+		case !existed:
+			e.Added = append(e.Added, name)
+		case before.sum != after.sum:
+			e.Modified = append(e.Modified, name)
+		default:
+			continue
+		}
+		diff.WriteString(fileDiff(name, before, after, existed, exists))
+	}
+	if len(e.Added)+len(e.Removed)+len(e.Modified) == 0 {
+		return nil
+	}
+	e.Diff = diff.String()
+	return e
+}
+
+// CheckUnchanged runs deps in order and fails with a *WorktreeChangedError,
+// if they changed any files in the project root directory.
+func (m *Manager) CheckUnchanged(ctx context.Context, parent DependencyIDer, deps ...Dependency) error {
+	root, err := cache.ProjectRoot()
+	if err != nil {
+		return err
+	}
+	snapshot, err := TakeSnapshot(ctx, root)
+	if err != nil {
+		return err
+	}
+	if err := m.SerialDeps(ctx, parent, deps...); err != nil {
+		return err
+	}
+	return snapshot.Check(ctx)
+}
+
+// WorktreeChangedError is returned when files changed compared to a Snapshot.
+type WorktreeChangedError struct {
+	Dir string
+	// Paths relative to Dir.
+	Added, Removed, Modified []string
+	// Unified diff of all changes.
+	Diff string
+}
+
+func (e *WorktreeChangedError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "files in %s changed:", e.Dir)
+	for _, f := range e.Modified {
+		b.WriteString("\n  modified: " + f)
+	}
+	for _, f := range e.Added {
+		b.WriteString("\n  added:    " + f)
+	}
+	for _, f := range e.Removed {
+		b.WriteString("\n  removed:  " + f)
+	}
+	b.WriteString("\n\n" + e.Diff)
+	return b.String()
+}
+
+// Returns a unified diff for a single file.
+// Binary and large files, as well as files with too many changes, are only reported as changed.
+func fileDiff(name string, before, after snapshotFile, existed, exists bool) string {
+	from, to := "a/"+name, "b/"+name
+	if !existed {
+		from = "/dev/null"
+	}
+	if !exists {
+		to = "/dev/null"
+	}
+	if existed && before.data == nil || exists && after.data == nil {
+		return fmt.Sprintf("Files %s and %s differ\n", from, to)
+	}
+	if bytes.IndexByte(before.data, 0) >= 0 || bytes.IndexByte(after.data, 0) >= 0 {
+		return fmt.Sprintf("Binary files %s and %s differ\n", from, to)
+	}
+	hunks, ok := unifiedDiff(splitLines(before.data), splitLines(after.data))
+	if !ok {
+		return fmt.Sprintf("Files %s and %s differ in more than %d lines\n", from, to, maxDiffEdits)
+	}
+	return fmt.Sprintf("--- %s\n+++ %s\n", from, to) + hunks
+}
+
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+const diffContext = 3
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// Returns the hunks of a unified diff between a and b.
+// ok is false if a and b differ in more than maxDiffEdits lines.
+func unifiedDiff(a, b []string) (hunks string, ok bool) {
+	ops, ok := diffLines(a, b)
+	if !ok {
+		return "", false
+	}
+
+	// Line numbers in a and b before each op.
+	type pos struct{ a, b int }
+	positions := make([]pos, len(ops)+1)
+	for i, op := range ops {
+		p := positions[i]
+		if op.kind != '+' {
+			p.a++
+		}
+		if op.kind != '-' {
+			p.b++
+		}
+		positions[i+1] = p
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		// Extend the hunk until changes are separated by more than twice the context.
+		start, end := max(0, i-diffContext), i
+		for j := i; j < len(ops) && j-end <= 2*diffContext; j++ {
+			if ops[j].kind != ' ' {
+				end = j + 1
+			}
+		}
+		end = min(len(ops), end+diffContext)
+
+		from, to := positions[start], positions[end]
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(from.a, to.a-from.a), hunkRange(from.b, to.b-from.b))
+		for _, op := range ops[start:end] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+	return out.String(), true
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		// Empty ranges refer to the line before.
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// Maximum number of inserted and deleted lines diffLines computes a script for.
+// Memory is quadratic in the number of edits.
+const maxDiffEdits = 2000
+
+// Computes a shortest edit script from a to b using Myers' algorithm.
+// ok is false if more than maxDiffEdits edits are needed.
+func diffLines(a, b []string) (ops []diffOp, ok bool) {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	// Only diagonals -d-1..d+1 are needed to walk back from step d,
+	// trace[d][i] holds v[offset-d-1+i] before step d.
+	var trace [][]int
+
+	found := false
+search:
+	for d := 0; d <= min(n+m, maxDiffEdits); d++ {
+		trace = append(trace, slices.Clone(v[offset-d-1:offset+d+2]))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break search
+			}
+		}
+	}
+	if !found {
+		return nil, false
+	}
+
+	// Walk back through the trace to collect the edits.
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d+1] }
+		k := x - y
+		prevK := k - 1
+		if k == -d || k != d && at(k-1) < at(k+1) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			ops = append(ops, diffOp{' ', a[x-1]})
+			x--
+			y--
+		}
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			ops = append(ops, diffOp{'+', b[y-1]})
+			y--
+		} else {
+			ops = append(ops, diffOp{'-', a[x-1]})
+			x--
+		}
+	}
+	slices.Reverse(ops)
+	return ops, true
+}
@@ -0,0 +1,196 @@
+package run
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pkg.package-operator.run/cardboard/cache"
+	"pkg.package-operator.run/cardboard/sh"
+)
+
+func TestSnapshot_Check(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(cache.DirEnv, filepath.Join(dir, ".cache"))
+	write := func(name, content string) {
+		t.Helper()
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+	write("a.go", "package a\n// A.\nfunc A() {}\n")
+	write("removed.txt", "bye\n")
+	write("same/b.txt", "b\n")
+	write(".git/HEAD", "ref: refs/heads/main\n")
+
+	s, err := TakeSnapshot(t.Context(), dir)
+	require.NoError(t, err)
+	require.NoError(t, s.Check(t.Context()))
+
+	write("a.go", "package a\n// A.\nfunc A() { _ = 1 }\n")
+	write("new.txt", "hello")
+	require.NoError(t, os.Remove(filepath.Join(dir, "removed.txt")))
+	write(".git/HEAD", "ref: refs/heads/other\n")
+	write(".cache/bin/tool", "\x00binary")
+
+	err = s.Check(t.Context())
+	var changedErr *WorktreeChangedError
+	require.ErrorAs(t, err, &changedErr)
+	assert.Equal(t, []string{"a.go"}, changedErr.Modified)
+	assert.Equal(t, []string{"new.txt"}, changedErr.Added)
+	assert.Equal(t, []string{"removed.txt"}, changedErr.Removed)
+	assert.Equal(t, `--- a/a.go
++++ b/a.go
+@@ -1,3 +1,3 @@
+ package a
+ // A.
+-func A() {}
++func A() { _ = 1 }
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1 @@
++hello
+\ No newline at end of file
+--- a/removed.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+`, changedErr.Diff)
+	assert.Contains(t, err.Error(), "\n  modified: a.go\n  added:    new.txt\n  removed:  removed.txt\n")
+}
+
+func TestSnapshot_Check_git(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(cache.DirEnv, filepath.Join(dir, ".cache"))
+	write := func(name, content string) {
+		t.Helper()
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+	_, err := sh.New(sh.WithWorkDir(dir)).Output(t.Context(), "git", "init", "-q")
+	require.NoError(t, err)
+	write(".gitignore", "/bin/\n")
+	write("tracked.txt", "a\n")
+	write("bin/tool", "old")
+
+	s, err := TakeSnapshot(t.Context(), dir)
+	require.NoError(t, err)
+	assert.NotContains(t, s.files, "bin/tool", "ignored files are skipped")
+
+	write("bin/tool", "new")
+	require.NoError(t, s.Check(t.Context()))
+
+	// Large files are not kept in memory and not diffed.
+	write("tracked.txt", strings.Repeat("a\n", maxDiffFileSize))
+	var changedErr *WorktreeChangedError
+	require.ErrorAs(t, s.Check(t.Context()), &changedErr)
+	assert.Equal(t, []string{"tracked.txt"}, changedErr.Modified)
+	assert.Equal(t, "Files a/tracked.txt and b/tracked.txt differ\n", changedErr.Diff)
+}
+
+func Test_fileDiff_tooManyEdits(t *testing.T) {
+	t.Parallel()
+	file := func(prefix string) snapshotFile {
+		var b strings.Builder
+		for i := range maxDiffEdits {
+			fmt.Fprintf(&b, "%s%d\n", prefix, i)
+		}
+		return snapshotFile{data: []byte(b.String())}
+	}
+	assert.Equal(t, "Files a/gen.go and b/gen.go differ in more than 2000 lines\n",
+		fileDiff("gen.go", file("a"), file("b"), true, true))
+}
+
+func Test_unifiedDiff(t *testing.T) {
+	t.Parallel()
+
+	lines := func(s string) []string { return splitLines([]byte(s)) }
+	a := lines("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n17\n18\n19\n20\n")
+
+	tests := []struct {
+		name     string
+		b        []string
+		expected string
+	}{
+		{
+			name:     "unchanged",
+			b:        a,
+			expected: "",
+		},
+		{
+			name: "separate hunks",
+			b:    lines("x\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n17\n18\n19\n20\n21\n"),
+			expected: `@@ -1,4 +1,4 @@
+-1
++x
+ 2
+ 3
+ 4
+@@ -18,3 +18,4 @@
+ 18
+ 19
+ 20
++21
+`,
+		},
+		{
+			name: "merged hunks",
+			b:    lines("1\n2\n3\n4\nx\n6\n7\n8\n9\n10\n11\ny\n13\n14\n15\n16\n17\n18\n19\n20\n"),
+			expected: `@@ -2,14 +2,14 @@
+ 2
+ 3
+ 4
+-5
++x
+ 6
+ 7
+ 8
+ 9
+ 10
+ 11
+-12
++y
+ 13
+ 14
+ 15
+`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			hunks, ok := unifiedDiff(a, test.b)
+			require.True(t, ok)
+			assert.Equal(t, test.expected, hunks)
+		})
+	}
+}
+
+func Test_diffLines(t *testing.T) {
+	t.Parallel()
+	a := strings.Split("a b c a b b a", " ")
+	b := strings.Split("c b a b a c", " ")
+
+	ops, ok := diffLines(a, b)
+	require.True(t, ok)
+	var gotA, gotB []string
+	changes := 0
+	for _, op := range ops {
+		if op.kind != '+' {
+			gotA = append(gotA, op.line)
+		}
+		if op.kind != '-' {
+			gotB = append(gotB, op.line)
+		}
+		if op.kind != ' ' {
+			changes++
+		}
+	}
+	assert.Equal(t, a, gotA)
+	assert.Equal(t, b, gotB)
+	assert.Equal(t, 5, changes, "shortest edit script")
+}
@@ -0,0 +1,247 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"pkg.package-operator.run/cardboard/cache"
+)
+
+// Inputs of targets, keyed by target ID like "Dev:Unit".
+// Watch mode reruns a target when files matching its globs change.
+// Globs use filepath.Match syntax and are relative to the project root,
+// matched directories are watched recursively.
+// Targets without declared inputs are rerun when Go files anywhere in the project change.
+type WithWatchInputs map[string][]string
+
+func (wi WithWatchInputs) ApplyToManager(m *Manager) {
+	m.watchInputs = wi
+}
+
+// Time to wait for further changes before a target is rerun.
+var watchDebounce = 300 * time.Millisecond
+
+// Receives changed file paths.
+type watcher interface {
+	Events() <-chan string
+	Close() error
+}
+
+// Runs the target and reruns it whenever its inputs change.
+// A change cancels the in-flight run, which is restarted once it returned.
+// Returns when ctx is cancelled.
+func (m *Manager) watch(ctx context.Context, id string, args []string) error {
+	if _, ok := m.targets[id]; !ok {
+		return &UnknownTargetError{ID: id}
+	}
+	root, err := cache.ProjectRoot()
+	if err != nil {
+		return err
+	}
+	dirs, match, err := m.watchedInputs(root, id)
+	if err != nil {
+		return err
+	}
+	w, err := newWatcher(dirs)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	changes := debounce(ctx, w.Events(), match, watchDebounce)
+
+	for cycle := 1; ; cycle++ {
+		fmt.Fprintf(m.stderr, "[watch] cycle %d: running %s\n", cycle, id)
+		runCtx, cancel := context.WithCancel(ctx)
+		done := make(chan error, 1)
+		go func() { done <- m.runTarget(runCtx, id, args) }()
+
+		var changed []string
+		select {
+		case err := <-done:
+			cancel()
+			m.printWatchReport(err)
+			fmt.Fprintf(m.stderr, "[watch] waiting for changes in %d directories\n", len(dirs))
+			select {
+			case <-ctx.Done():
+				return nil
+			case changed = <-changes:
+			}
+		case changed = <-changes:
+			cancel()
+			<-done
+			fmt.Fprintln(m.stderr, "[watch] cancelled in-flight run")
+		case <-ctx.Done():
+			cancel()
+			<-done
+			return nil
+		}
+		if changed == nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.New("watching files failed")
+		}
+		fmt.Fprintf(m.stderr, "[watch] changed: %s\n", summarizeFiles(root, changed))
+		m.resetRun()
+	}
+}
+
+// Replaces the dependency run, so all dependencies run again.
+func (m *Manager) resetRun() {
+	dr := newDependencyRun()
+	dr.parallelism = m.dr.parallelism
+	dr.output = m.dr.output
+	dr.noColor = m.dr.noColor
+	m.dr = dr
+	m.dm.dr = dr
+}
+
+func (m *Manager) printWatchReport(err error) {
+	if m.rc.ReportFormat != ReportFormatNone {
+		fmt.Fprint(m.stderr, m.dr.compactReport())
+		_ = m.background.report(m.stderr)
+	}
+	if err != nil {
+		fmt.Fprintln(m.stderr, m.dr.colorize(err.Error(), redColor))
+	}
+}
+
+func summarizeFiles(root string, files []string) string {
+	rel := make([]string, len(files))
+	for i, f := range files {
+		if r, err := filepath.Rel(root, f); err == nil {
+			f = r
+		}
+		rel[i] = f
+	}
+	if len(rel) > 3 {
+		return strings.Join(rel[:3], ", ") + fmt.Sprintf(" and %d more", len(rel)-3)
+	}
+	return strings.Join(rel, ", ")
+}
+
+// Returns the directories to watch for the given target
+// and a function reporting whether a changed file is an input.
+func (m *Manager) watchedInputs(root, id string) ([]string, func(string) bool, error) {
+	globs, ok := m.watchInputs[id]
+	if !ok {
+		// All directories are watched, new packages may be created in directories without Go files.
+		var dirs []string
+		err := walkDirs(root, func(dir string) { dirs = append(dirs, dir) })
+		return dirs, isGoInput, err
+	}
+
+	dirSet := map[string]struct{}{}
+	var recursive []string
+	for _, glob := range globs {
+		matches, err := filepath.Glob(filepath.Join(root, glob))
+		if err != nil {
+			return nil, nil, fmt.Errorf("watch input %q: %w", glob, err)
+		}
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !info.IsDir() {
+				dirSet[filepath.Dir(match)] = struct{}{}
+				continue
+			}
+			recursive = append(recursive, match)
+			err = walkDirs(match, func(dir string) { dirSet[dir] = struct{}{} })
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	match := func(file string) bool {
+		for _, dir := range recursive {
+			if strings.HasPrefix(file, dir+string(filepath.Separator)) {
+				return true
+			}
+		}
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return false
+		}
+		return slices.ContainsFunc(globs, func(glob string) bool {
+			ok, _ := filepath.Match(filepath.Clean(glob), rel)
+			return ok
+		})
+	}
+	return slices.Sorted(maps.Keys(dirSet)), match, nil
+}
+
+func isGoInput(file string) bool {
+	switch name := filepath.Base(file); name {
+	case "go.mod", "go.sum", "go.work", "go.work.sum":
+		return true
+	default:
+		return strings.HasSuffix(name, ".go") && !strings.HasPrefix(name, ".")
+	}
+}
+
+// Calls fn for dir and all directories below, skipping hidden directories like .git or the cache.
+func walkDirs(dir string, fn func(dir string)) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if p != dir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		fn(p)
+		return nil
+	})
+}
+
+// Collects matching events until no new events arrived for delay and sends them as a sorted batch.
+// The returned channel is closed when events is closed.
+func debounce(ctx context.Context, events <-chan string, match func(string) bool, delay time.Duration) <-chan []string {
+	out := make(chan []string)
+	go func() {
+		defer close(out)
+		var (
+			pending []string
+			timer   <-chan time.Time
+		)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case file, ok := <-events:
+				if !ok {
+					return
+				}
+				if !match(file) {
+					continue
+				}
+				if !slices.Contains(pending, file) {
+					pending = append(pending, file)
+				}
+				timer = time.After(delay)
+			case <-timer:
+				slices.Sort(pending)
+				select {
+				case out <- pending:
+				case <-ctx.Done():
+					return
+				}
+				pending, timer = nil, nil
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,127 @@
+//go:build linux
+
+package run
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+const inotifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_CREATE | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+
+// Watches directories via inotify.
+// Directories created below watched directories are watched as well.
+type inotifyWatcher struct {
+	fd     int
+	f      *os.File
+	dirs   map[int32]string
+	events chan string
+	done   chan struct{}
+}
+
+func newWatcher(dirs []string) (watcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("initializing inotify: %w", err)
+	}
+	w := &inotifyWatcher{
+		fd: fd,
+		// Non-blocking file descriptors use the runtime poller, so Close interrupts pending reads.
+		f:      os.NewFile(uintptr(fd), "inotify"),
+		dirs:   map[int32]string{},
+		events: make(chan string),
+		done:   make(chan struct{}),
+	}
+	for _, dir := range dirs {
+		if err := w.add(dir); err != nil {
+			w.f.Close()
+			return nil, err
+		}
+	}
+	go w.read()
+	return w, nil
+}
+
+func (w *inotifyWatcher) add(dir string) error {
+	wd, err := syscall.InotifyAddWatch(w.fd, dir, inotifyMask)
+	if err != nil {
+		return fmt.Errorf("watching %s: %w", dir, err)
+	}
+	w.dirs[int32(wd)] = dir //nolint:gosec // watch descriptors are int32 in the kernel ABI.
+	return nil
+}
+
+// Watches a new directory and everything below it.
+// Returns files created before the watches were added, so their events are not lost.
+func (w *inotifyWatcher) addCreated(dir string) []string {
+	var files []string
+	_ = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		switch {
+		case err != nil:
+			return nil
+		case !d.IsDir():
+			files = append(files, p)
+			return nil
+		case strings.HasPrefix(d.Name(), "."):
+			return filepath.SkipDir
+		}
+		// The directory may be gone already, which its parent reports.
+		_ = w.add(p)
+		return nil
+	})
+	return files
+}
+
+func (w *inotifyWatcher) Events() <-chan string { return w.events }
+
+func (w *inotifyWatcher) Close() error {
+	close(w.done)
+	return w.f.Close()
+}
+
+func (w *inotifyWatcher) read() {
+	defer close(w.events)
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := w.f.Read(buf)
+		if err != nil {
+			return
+		}
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			// struct inotify_event {int32 wd; uint32 mask, cookie, len; char name[];}
+			wd := int32(binary.NativeEndian.Uint32(buf[offset:])) //nolint:gosec // see above.
+			mask := binary.NativeEndian.Uint32(buf[offset+4:])
+			nameLen := int(binary.NativeEndian.Uint32(buf[offset+12:]))
+			nameStart := offset + syscall.SizeofInotifyEvent
+			name := strings.TrimRight(string(buf[nameStart:nameStart+nameLen]), "\x00")
+			offset = nameStart + nameLen
+
+			if mask&syscall.IN_IGNORED != 0 {
+				// Removed directory.
+				delete(w.dirs, wd)
+				continue
+			}
+			dir, ok := w.dirs[wd]
+			if !ok || len(name) == 0 {
+				continue
+			}
+			changed := []string{filepath.Join(dir, name)}
+			if mask&syscall.IN_ISDIR != 0 && mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+				changed = append(changed, w.addCreated(changed[0])...)
+			}
+			for _, file := range changed {
+				select {
+				case w.events <- file:
+				case <-w.done:
+					return
+				}
+			}
+		}
+	}
+}
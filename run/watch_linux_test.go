@@ -0,0 +1,97 @@
+//go:build linux
+
+package run
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pkg.package-operator.run/cardboard/cache"
+)
+
+func Test_inotifyWatcher(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	w, err := newWatcher([]string{dir})
+	require.NoError(t, err)
+
+	file := filepath.Join(dir, "a.go")
+	require.NoError(t, os.WriteFile(file, []byte("package a\n"), 0o600))
+	assert.Equal(t, file, <-w.Events())
+
+	require.NoError(t, w.Close())
+	for range w.Events() {
+		// drain until closed.
+	}
+}
+
+func Test_inotifyWatcher_newDirectory(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	w, err := newWatcher([]string{dir})
+	require.NoError(t, err)
+	defer w.Close()
+
+	pkg := filepath.Join(dir, "pkg")
+	require.NoError(t, os.Mkdir(pkg, 0o755))
+	assert.Equal(t, pkg, <-w.Events())
+
+	file := filepath.Join(pkg, "a.go")
+	require.NoError(t, os.WriteFile(file, []byte("package a\n"), 0o600))
+	for event := range w.Events() {
+		// The file may be reported for its creation and its write.
+		if event == file {
+			return
+		}
+	}
+	t.Fatal("no event for file in new directory")
+}
+
+type WatchThing struct {
+	runs chan context.Context
+}
+
+func (w *WatchThing) Test(ctx context.Context, _ []string) error {
+	w.runs <- ctx
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+//nolint:paralleltest // changes the working directory.
+func TestManager_watch(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/w\n"), 0o600))
+	t.Chdir(root)
+	t.Setenv(cache.DirEnv, filepath.Join(root, ".cache"))
+
+	debounce := watchDebounce
+	watchDebounce = 10 * time.Millisecond
+	t.Cleanup(func() { watchDebounce = debounce })
+
+	var stderr syncBuffer
+	thing := &WatchThing{runs: make(chan context.Context)}
+	m := New(WithStderr{&stderr}, WithStdout{&stderr})
+	require.NoError(t, m.Register(thing))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+	go func() { done <- m.watch(ctx, "WatchThing:Test", nil) }()
+
+	first := <-thing.runs
+	require.NoError(t, os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n"), 0o600))
+	second := <-thing.runs
+	require.ErrorIs(t, first.Err(), context.Canceled, "in-flight run is cancelled")
+	require.NoError(t, second.Err())
+
+	cancel()
+	require.NoError(t, <-done)
+	assert.Contains(t, string(stderr.Bytes()), "[watch] cycle 1: running WatchThing:Test\n")
+	assert.Contains(t, string(stderr.Bytes()), "[watch] cancelled in-flight run\n[watch] changed: main.go\n")
+	assert.Contains(t, string(stderr.Bytes()), "[watch] cycle 2: running WatchThing:Test\n")
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package run
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func newWatcher([]string) (watcher, error) {
+	return nil, fmt.Errorf("watch mode is not supported on %s", runtime.GOOS)
+}
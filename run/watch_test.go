@@ -0,0 +1,66 @@
+package run
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_debounce(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	events := make(chan string)
+	batches := debounce(ctx, events, isGoInput, 50*time.Millisecond)
+	for _, e := range []string{"b.go", "a.go", "b.go", "notes.txt", ".#lock.go"} {
+		events <- e
+	}
+	assert.Equal(t, []string{"a.go", "b.go"}, <-batches)
+
+	events <- "go.mod"
+	assert.Equal(t, []string{"go.mod"}, <-batches)
+
+	close(events)
+	_, ok := <-batches
+	assert.False(t, ok, "closed with events")
+}
+
+func TestManager_watchedInputs(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	for _, f := range []string{
+		"go.mod", "cmd/main.go", "pkg/a/a.go", "docs/index.md", "docs/img/logo.svg", "config.yaml", ".git/config",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, filepath.Dir(f)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, f), nil, 0o600))
+	}
+	m := New(WithWatchInputs{"Docs:Build": {"docs", "*.yaml"}})
+
+	dirs, match, err := m.watchedInputs(root, "Dev:Unit")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		root, filepath.Join(root, "cmd"), filepath.Join(root, "docs"), filepath.Join(root, "docs", "img"),
+		filepath.Join(root, "pkg"), filepath.Join(root, "pkg", "a"),
+	}, dirs, "all but hidden directories")
+	assert.True(t, match(filepath.Join(root, "pkg", "a", "new.go")))
+	assert.False(t, match(filepath.Join(root, "docs", "index.md")))
+
+	dirs, match, err = m.watchedInputs(root, "Docs:Build")
+	require.NoError(t, err)
+	assert.Equal(t, []string{root, filepath.Join(root, "docs"), filepath.Join(root, "docs", "img")}, dirs)
+	assert.True(t, match(filepath.Join(root, "docs", "img", "new.png")))
+	assert.True(t, match(filepath.Join(root, "other.yaml")))
+	assert.False(t, match(filepath.Join(root, "main.go")))
+}
+
+func Test_summarizeFiles(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "a.go, b/c.go", summarizeFiles("/root", []string{"/root/a.go", "/root/b/c.go"}))
+	assert.Equal(t, "1, 2, 3 and 2 more", summarizeFiles("/root", []string{"1", "2", "3", "4", "5"}))
+}
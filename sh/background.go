@@ -0,0 +1,180 @@
+package sh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// Default time a background process has to become ready.
+	defaultReadyTimeout = time.Minute
+	// Time a process has to exit after SIGTERM, before it is killed.
+	stopTimeout = 5 * time.Second
+	// Default interval between probe checks.
+	defaultProbeInterval = 100 * time.Millisecond
+)
+
+// Probe reports when a background process is ready to be used.
+type Probe interface {
+	// Blocks until the process is ready, fails if ctx is done or the process exited.
+	WaitReady(ctx context.Context, p *Process) error
+}
+
+// Ready when a TCP connection to Addr, e.g. "localhost:5000", can be established.
+type TCPProbe struct {
+	Addr     string
+	Interval time.Duration
+}
+
+func (t TCPProbe) WaitReady(ctx context.Context, p *Process) error {
+	var d net.Dialer
+	return poll(ctx, p, t.Interval, func(ctx context.Context) error {
+		conn, err := d.DialContext(ctx, "tcp", t.Addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	})
+}
+
+// Ready when a GET request to URL returns a 2xx status code.
+type HTTPProbe struct {
+	URL      string
+	Interval time.Duration
+}
+
+func (h HTTPProbe) WaitReady(ctx context.Context, p *Process) error {
+	return poll(ctx, p, h.Interval, func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.URL, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("GET %s: %s", h.URL, resp.Status)
+		}
+		return nil
+	})
+}
+
+// Ready when the process printed a line matching Pattern to stdout or stderr.
+type LogProbe struct {
+	Pattern *regexp.Regexp
+}
+
+func (l LogProbe) WaitReady(ctx context.Context, p *Process) error {
+	_, err := p.WaitForLine(ctx, l.Pattern)
+	return err
+}
+
+// Ready when all probes report ready.
+type Probes []Probe
+
+func (ps Probes) WaitReady(ctx context.Context, p *Process) error {
+	for _, probe := range ps {
+		if err := probe.WaitReady(ctx, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Calls check every interval until it succeeds.
+func poll(ctx context.Context, p *Process, interval time.Duration, check func(ctx context.Context) error) error {
+	if interval == 0 {
+		interval = defaultProbeInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var lastErr error
+	for {
+		err := check(ctx)
+		if err == nil {
+			return nil
+		}
+		// Keep the cause of the last complete check, instead of the interruption by ctx.
+		if ctx.Err() == nil || lastErr == nil {
+			lastErr = err
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w, last error: %w", ctx.Err(), lastErr)
+		case <-p.Done():
+			return fmt.Errorf("process exited: %w", errors.Join(p.Wait(), lastErr))
+		case <-ticker.C:
+		}
+	}
+}
+
+type lifecycleContextKey struct{}
+
+// Lifecycle owns background processes and stops them when it ends.
+// The run.Manager stops background processes after each target and attaches their logs to the report.
+type Lifecycle interface {
+	Track(p *Process)
+}
+
+// ContextWithLifecycle returns a copy of ctx that makes Runners hand background processes to l.
+func ContextWithLifecycle(ctx context.Context, l Lifecycle) context.Context {
+	return context.WithValue(ctx, lifecycleContextKey{}, l)
+}
+
+// LifecycleFromContext returns the Lifecycle set via ContextWithLifecycle, if any.
+func LifecycleFromContext(ctx context.Context) (Lifecycle, bool) {
+	l, ok := ctx.Value(lifecycleContextKey{}).(Lifecycle)
+	return l, ok
+}
+
+// Background starts a long-running command in its own process group,
+// e.g. a local registry or a server under test, and waits until probe reports it ready.
+// probe may be nil to not wait at all, it is skipped in dry run mode.
+//
+// The process group is terminated when ctx is cancelled or when the Lifecycle in ctx ends.
+// Without a Lifecycle, callers are responsible to call Stop.
+func (r *Runner) Background(ctx context.Context, probe Probe, cmd string, args ...string) (*Process, error) {
+	p, err := r.start(ctx, true, cmd, args...)
+	if err != nil {
+		return nil, err
+	}
+	if l, ok := LifecycleFromContext(ctx); ok {
+		l.Track(p)
+	}
+	// Processes faked by a Recorder in dry run mode exit right away and are not probed.
+	if probe == nil || p.process == nil {
+		return p, nil
+	}
+
+	readyTimeout := r.readyTimeout
+	if readyTimeout == 0 {
+		readyTimeout = defaultReadyTimeout
+	}
+	readyCtx, cancel := context.WithTimeout(ctx, readyTimeout)
+	defer cancel()
+	if err := probe.WaitReady(readyCtx, p); err != nil {
+		_ = p.Stop()
+		return nil, fmt.Errorf(`waiting for "%s %s" to become ready: %w%s`,
+			cmd, strings.Join(args, " "), err, formatLines(p.Lines()))
+	}
+	return p, nil
+}
+
+func formatLines(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	const maxLines = 20
+	if len(lines) > maxLines {
+		lines = lines[len(lines)-maxLines:]
+	}
+	return "\nlast output:\n  " + strings.Join(lines, "\n  ")
+}
@@ -0,0 +1,99 @@
+//go:build linux
+
+package sh_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pkg.package-operator.run/cardboard/sh"
+)
+
+type testLifecycle struct{ tracked []*sh.Process }
+
+func (l *testLifecycle) Track(p *sh.Process) { l.tracked = append(l.tracked, p) }
+
+func TestRunner_Background(t *testing.T) {
+	t.Parallel()
+	l := &testLifecycle{}
+	ctx := sh.ContextWithLifecycle(t.Context(), l)
+	r := sh.New(sh.WithCombinedOutput{io.Discard})
+
+	p, err := r.Background(ctx, sh.LogProbe{Pattern: regexp.MustCompile("^ready$")},
+		"bash", "-c", "sleep 60 & echo child $!; sleep 0.1; echo ready; wait")
+	require.NoError(t, err)
+	assert.Equal(t, []*sh.Process{p}, l.tracked)
+
+	line, err := p.WaitForLine(t.Context(), regexp.MustCompile("^child "))
+	require.NoError(t, err)
+	child, err := strconv.Atoi(strings.TrimPrefix(line, "child "))
+	require.NoError(t, err)
+
+	require.NoError(t, p.Stop())
+	assert.Eventually(t, func() bool { return processStopped(child) },
+		time.Second, 10*time.Millisecond, "children are stopped with the process group")
+}
+
+func processStopped(pid int) bool {
+	// Orphaned children may linger as zombies, until reaped by init.
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	return errors.Is(err, os.ErrNotExist) || strings.Contains(string(stat), ") Z ")
+}
+
+func TestRunner_Background_probes(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+
+	r := sh.New(sh.WithCombinedOutput{io.Discard})
+	p, err := r.Background(t.Context(), sh.Probes{
+		sh.TCPProbe{Addr: srv.Listener.Addr().String()},
+		sh.HTTPProbe{URL: srv.URL},
+	}, "sleep", "60")
+	require.NoError(t, err)
+	require.NoError(t, p.Stop())
+}
+
+func TestRunner_Background_notReady(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	r := sh.New(sh.WithCombinedOutput{io.Discard}, sh.WithReadyTimeout(200*time.Millisecond))
+	_, err := r.Background(t.Context(), sh.HTTPProbe{URL: srv.URL, Interval: 10 * time.Millisecond},
+		"bash", "-c", "echo starting; sleep 60")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), `waiting for "bash -c echo starting; sleep 60" to become ready: `)
+	assert.Contains(t, err.Error(), "503 Service Unavailable\nlast output:\n  starting")
+
+	_, err = r.Background(t.Context(), sh.TCPProbe{Addr: "127.0.0.1:1"}, "bash", "-c", "echo boom; exit 1")
+	require.ErrorContains(t, err, `process exited: running "bash -c echo boom; exit 1" failed with exit code 1`)
+}
+
+func TestRunner_Background_cancel(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(t.Context())
+	p, err := sh.New(sh.WithCombinedOutput{io.Discard}).Background(ctx, nil, "sleep", "60")
+	require.NoError(t, err)
+
+	cancel()
+	select {
+	case <-p.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("process not stopped on cancellation")
+	}
+}
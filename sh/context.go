@@ -0,0 +1,56 @@
+package sh
+
+import (
+	"context"
+	"io"
+	"os"
+)
+
+type outputContextKey struct{}
+
+// ContextWithOutput returns a copy of ctx that makes Runners without explicitly
+// configured stdout or stderr writers write both streams to w,
+// instead of os.Stdout and os.Stderr.
+func ContextWithOutput(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, outputContextKey{}, w)
+}
+
+// OutputFromContext returns the writer set via ContextWithOutput, if any.
+func OutputFromContext(ctx context.Context) (io.Writer, bool) {
+	w, ok := ctx.Value(outputContextKey{}).(io.Writer)
+	return w, ok
+}
+
+type noLogPrefixContextKey struct{}
+
+// ContextWithoutLogPrefix returns a copy of ctx that makes Runners write output lines
+// without prefixing them with the command and stream.
+func ContextWithoutLogPrefix(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noLogPrefixContextKey{}, true)
+}
+
+// Lines are prefixed unless disabled via ContextWithoutLogPrefix or the CARDBOARD_NO_LOG_PREFIX environment variable.
+func logPrefixFromContext(ctx context.Context) bool {
+	if noPrefix, ok := ctx.Value(noLogPrefixContextKey{}).(bool); ok {
+		return !noPrefix
+	}
+	return os.Getenv("CARDBOARD_NO_LOG_PREFIX") == ""
+}
+
+type commandHookContextKey struct{}
+
+// CommandHook is called with the command before a Runner executes it.
+// script is the input of scripts run via Runner.Bash and empty otherwise.
+// Returning an error aborts the command.
+type CommandHook func(ctx context.Context, cmd string, args []string, script string) error
+
+// ContextWithCommandHook returns a copy of ctx that makes Runners call hook before executing commands.
+func ContextWithCommandHook(ctx context.Context, hook CommandHook) context.Context {
+	return context.WithValue(ctx, commandHookContextKey{}, hook)
+}
+
+// CommandHookFromContext returns the hook set via ContextWithCommandHook, if any.
+func CommandHookFromContext(ctx context.Context) (CommandHook, bool) {
+	hook, ok := ctx.Value(commandHookContextKey{}).(CommandHook)
+	return hook, ok
+}
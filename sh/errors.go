@@ -0,0 +1,126 @@
+package sh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Bytes of stderr kept for ExitError.
+const stderrTailSize = 4096
+
+// ExitError is returned when a command ran, but did not exit successfully.
+type ExitError struct {
+	Cmd  string
+	Args []string
+	// Exit code of the command, -1 if it was terminated by a signal.
+	ExitCode int
+	// Signal that terminated the command, nil if it exited on its own.
+	Signal os.Signal
+	// Time the command ran.
+	Duration time.Duration
+	// Timeout of the runner, if the command was stopped because it exceeded it.
+	Timeout time.Duration
+	// Last bytes of stderr.
+	Stderr string
+	// Underlying *exec.ExitError or the fake error of a Recorder in dry run mode.
+	Err error
+
+	// whether Stderr is part of the message, because it was not written anywhere else.
+	showStderr bool
+}
+
+func (e *ExitError) Error() string {
+	cmdline := e.Cmd + " " + strings.Join(e.Args, " ")
+	if e.Timeout > 0 {
+		return fmt.Sprintf(`running "%s" timed out after %s: %s`, cmdline, e.Timeout, e.Err)
+	}
+	if e.ExitCode < 0 {
+		return fmt.Sprintf(`failed to run "%s": %s`, cmdline, e.Err)
+	}
+	if e.showStderr && len(e.Stderr) > 0 {
+		return fmt.Sprintf(`running "%s" failed with exit code %d: %s`,
+			cmdline, e.ExitCode, strings.TrimRight(e.Stderr, "\n"))
+	}
+	return fmt.Sprintf(`running "%s" failed with exit code %d`, cmdline, e.ExitCode)
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports commands stopped by the runners timeout as context.DeadlineExceeded.
+func (e *ExitError) Is(target error) bool {
+	return e.Timeout > 0 && target == context.DeadlineExceeded
+}
+
+// StartError is returned when a command could not be started, e.g. because the binary was not found.
+type StartError struct {
+	Cmd  string
+	Args []string
+	Err  error
+}
+
+func (e *StartError) Error() string {
+	return fmt.Sprintf(`failed to run "%s %s": %s`, e.Cmd, strings.Join(e.Args, " "), e.Err)
+}
+
+func (e *StartError) Unwrap() error {
+	return e.Err
+}
+
+// Wraps errors of running the command into an *ExitError or *StartError.
+func (e *execution) error(err error, took time.Duration, stderr *tailBuffer, showStderr bool) error {
+	// The command succeeded, only children kept stdout or stderr open longer than WaitDelay.
+	if err == nil || errors.Is(err, exec.ErrWaitDelay) {
+		return nil
+	}
+	var ee *exec.ExitError
+	if !errors.As(err, &ee) {
+		return &StartError{Cmd: e.cmd, Args: e.args, Err: err}
+	}
+
+	exitErr := &ExitError{
+		Cmd:        e.cmd,
+		Args:       e.args,
+		ExitCode:   exitStatus(err),
+		Duration:   took,
+		Err:        err,
+		showStderr: showStderr,
+	}
+	if stderr != nil {
+		exitErr.Stderr = stderr.String()
+	}
+	if !cmdRan(err) {
+		exitErr.ExitCode = -1
+		if ws, ok := ee.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			exitErr.Signal = ws.Signal()
+		}
+	}
+	if e.timedOut() {
+		exitErr.Timeout = e.timeout
+	}
+	return exitErr
+}
+
+// Keeps the last bytes written to it.
+type tailBuffer struct {
+	buf []byte
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if over := len(t.buf) - stderrTailSize; over > 0 {
+		t.buf = t.buf[over:]
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	return string(t.buf)
+}
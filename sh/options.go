@@ -1,9 +1,11 @@
 package sh
 
 import (
+	"context"
 	"io"
 	"log/slog"
 	"os"
+	"time"
 )
 
 type RunnerOption interface {
@@ -40,6 +42,47 @@ func (stderr WithStderr) ApplyToRunner(r *Runner) {
 	r.stderr = stderr.Writer
 }
 
+// Time background processes have to become ready, defaults to 1 minute.
+type WithReadyTimeout time.Duration
+
+func (t WithReadyTimeout) ApplyToRunner(r *Runner) {
+	r.readyTimeout = time.Duration(t)
+}
+
+// Starts commands in their own process group,
+// so cancellation and timeouts stop all child processes, e.g. of bash scripts, too.
+// Background processes always run in their own process group.
+type WithProcessGroup bool
+
+func (pg WithProcessGroup) ApplyToRunner(r *Runner) {
+	r.processGroup = bool(pg)
+}
+
+// Time commands have to exit after SIGTERM, when their context is cancelled or they timed out,
+// before they are killed with SIGKILL.
+// Without a wait delay, commands are killed immediately,
+// unless they run in their own process group, where the wait delay defaults to 5 seconds.
+type WithWaitDelay time.Duration
+
+func (wd WithWaitDelay) ApplyToRunner(r *Runner) {
+	r.waitDelay = time.Duration(wd)
+}
+
+// Maximum time each command may run, before it is stopped like on context cancellation.
+type WithTimeout time.Duration
+
+func (t WithTimeout) ApplyToRunner(r *Runner) {
+	r.timeout = time.Duration(t)
+}
+
+// Records executed commands and optionally fakes their results, see Recorder.
+// Takes precedence over a Recorder set via ContextWithRecorder.
+type WithRecorder struct{ *Recorder }
+
+func (rec WithRecorder) ApplyToRunner(r *Runner) {
+	r.recorder = rec.Recorder
+}
+
 type WithCombinedOutput struct{ io.Writer }
 
 func (out WithCombinedOutput) ApplyToRunner(r *Runner) {
@@ -47,18 +90,24 @@ func (out WithCombinedOutput) ApplyToRunner(r *Runner) {
 	r.stdout = out
 }
 
-func outOrStdoutIfNil(out io.Writer) io.Writer {
+func outOrStdoutIfNil(ctx context.Context, out io.Writer) io.Writer {
 	if out != nil {
 		return out
 	}
+	if ctxOut, ok := OutputFromContext(ctx); ok {
+		return ctxOut
+	}
 
 	return os.Stdout
 }
 
-func outOrStderrIfNil(out io.Writer) io.Writer {
+func outOrStderrIfNil(ctx context.Context, out io.Writer) io.Writer {
 	if out != nil {
 		return out
 	}
+	if ctxOut, ok := OutputFromContext(ctx); ok {
+		return ctxOut
+	}
 
 	return os.Stderr
 }
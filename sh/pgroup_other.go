@@ -0,0 +1,15 @@
+//go:build !unix
+
+package sh
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Process groups are not supported on this platform, only the process itself is stopped.
+func setProcessGroup(*exec.Cmd) {}
+
+func terminate(p *os.Process, _ bool) error { return p.Kill() }
+
+func kill(p *os.Process, _ bool) error { return p.Kill() }
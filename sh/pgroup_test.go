@@ -0,0 +1,138 @@
+//go:build linux
+
+package sh_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pkg.package-operator.run/cardboard/sh"
+)
+
+func TestRunner_WithProcessGroup(t *testing.T) {
+	t.Parallel()
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	ctx, cancel := context.WithCancel(t.Context())
+	r := sh.New(sh.WithCombinedOutput{io.Discard}, sh.WithProcessGroup(true))
+
+	done := make(chan error, 1)
+	go func() { done <- r.Bash(ctx, "sleep 60 &", "echo $! > "+pidFile, "wait") }()
+
+	var child int
+	require.Eventually(t, func() bool {
+		b, err := os.ReadFile(pidFile)
+		if err != nil {
+			return false
+		}
+		child, err = strconv.Atoi(strings.TrimSpace(string(b)))
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	select {
+	case err := <-done:
+		require.ErrorContains(t, err, "signal: terminated")
+	case <-time.After(5 * time.Second):
+		t.Fatal("command not stopped on cancellation")
+	}
+	assert.Eventually(t, func() bool { return processStopped(child) },
+		time.Second, 10*time.Millisecond, "children are stopped with the process group")
+}
+
+func TestRunner_WithWaitDelay(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(t.Context())
+	r := sh.New(sh.WithCombinedOutput{io.Discard}, sh.WithWaitDelay(5*time.Second))
+
+	p, err := r.Start(ctx, "bash", "-c",
+		"trap 'echo terminating; exit 3' TERM; sleep 60 >/dev/null 2>&1 & echo ready; wait")
+	require.NoError(t, err)
+	_, err = p.WaitForLine(t.Context(), regexp.MustCompile("^ready$"))
+	require.NoError(t, err)
+
+	cancel()
+	require.Error(t, p.Wait())
+	assert.Equal(t, 3, p.ExitCode(), "exited via SIGTERM handler")
+	assert.Contains(t, p.Lines(), "terminating")
+}
+
+func TestRunner_WithWaitDelay_kill(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(t.Context())
+	r := sh.New(sh.WithCombinedOutput{io.Discard}, sh.WithProcessGroup(true), sh.WithWaitDelay(100*time.Millisecond))
+
+	p, err := r.Start(ctx, "bash", "-c", "trap '' TERM; echo ready; sleep 60")
+	require.NoError(t, err)
+	_, err = p.WaitForLine(t.Context(), regexp.MustCompile("^ready$"))
+	require.NoError(t, err)
+
+	cancel()
+	select {
+	case <-p.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("process ignoring SIGTERM not killed after wait delay")
+	}
+	require.EqualError(t, p.Wait(), `failed to run "bash -c trap '' TERM; echo ready; sleep 60": signal: killed`)
+}
+
+func TestRunner_WithWaitDelay_orphan(t *testing.T) {
+	t.Parallel()
+	r := sh.New(sh.WithCombinedOutput{io.Discard}, sh.WithWaitDelay(200*time.Millisecond))
+
+	tests := []struct {
+		name   string
+		script string
+		cancel bool
+		err    string
+	}{
+		{
+			name:   "exited",
+			script: "sleep 8 & echo child $!",
+		},
+		{
+			name:   "cancelled",
+			script: "sleep 8 & echo child $!; wait",
+			cancel: true,
+			err:    "signal: terminated",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+			p, err := r.Start(ctx, "bash", "-c", test.script)
+			require.NoError(t, err)
+			line, err := p.WaitForLine(t.Context(), regexp.MustCompile("^child "))
+			require.NoError(t, err)
+			child, err := strconv.Atoi(strings.TrimPrefix(line, "child "))
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = syscall.Kill(child, syscall.SIGKILL) })
+
+			if test.cancel {
+				cancel()
+			}
+			select {
+			case <-p.Done():
+			case <-time.After(5 * time.Second):
+				t.Fatal("waiting for a process blocked by a child holding its output")
+			}
+			if len(test.err) > 0 {
+				require.ErrorContains(t, p.Wait(), test.err)
+			} else {
+				require.NoError(t, p.Wait())
+			}
+		})
+	}
+}
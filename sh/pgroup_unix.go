@@ -0,0 +1,40 @@
+//go:build unix
+
+package sh
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// Starts the command in a new process group, so it can be stopped including all children.
+func setProcessGroup(c *exec.Cmd) {
+	if c.SysProcAttr == nil {
+		c.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	c.SysProcAttr.Setpgid = true
+}
+
+// Sends SIGTERM to the process or its process group.
+func terminate(p *os.Process, group bool) error {
+	return signalProcess(p, group, syscall.SIGTERM)
+}
+
+// Sends SIGKILL to the process or its process group.
+func kill(p *os.Process, group bool) error {
+	return signalProcess(p, group, syscall.SIGKILL)
+}
+
+func signalProcess(p *os.Process, group bool, sig syscall.Signal) error {
+	if !group {
+		return p.Signal(sig)
+	}
+	if err := syscall.Kill(-p.Pid, sig); err != nil {
+		if err == syscall.ESRCH {
+			return os.ErrProcessDone
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,335 @@
+package sh
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Lines buffered per stream channel, further lines are dropped until the channel is read.
+	lineBufferSize = 1024
+	// Lines kept per process for WaitForLine and Lines.
+	lineHistorySize = 1000
+	// Longer lines are split.
+	maxLineSize = 1024 * 1024
+)
+
+// Process is a command started in the background via Runner.Start.
+type Process struct {
+	cmd     string
+	args    []string
+	pid     int
+	process *os.Process
+	// whether the process runs in its own process group.
+	group bool
+	// time the process has to exit after SIGTERM in Stop.
+	waitDelay time.Duration
+
+	stdout, stderr chan string
+	done           chan struct{}
+	err            error
+	exitCode       int
+
+	mux      sync.Mutex
+	history  []string
+	dropped  int
+	watchers []*lineWatcher
+}
+
+type lineWatcher struct {
+	re    *regexp.Regexp
+	match chan string
+}
+
+// Start starts the command and returns immediately.
+// Output is written line by line to the runners stdout and stderr, like Run,
+// and is available via the Stdout and Stderr channels of the returned Process.
+// The process is stopped when ctx is cancelled or the runners timeout is exceeded.
+func (r *Runner) Start(ctx context.Context, cmd string, args ...string) (*Process, error) {
+	return r.start(ctx, false, cmd, args...)
+}
+
+func (r *Runner) start(ctx context.Context, background bool, cmd string, args ...string) (*Process, error) {
+	e, err := r.command(ctx, background, "", cmd, args...)
+	if err != nil {
+		return nil, err
+	}
+	e.async = true
+	p := newProcess(e)
+	stdout, stderr, stderrTail := r.outputWriters(ctx, p)
+	if e.dryRun() {
+		e.done()
+		res := e.recorder.result(e.cmd, e.args, "")
+		_, _ = io.WriteString(stdout, res.Stdout)
+		_, _ = io.WriteString(stderr, res.Stderr)
+		err := e.fakeError(res, stderrTail, false)
+		e.record(err, 0)
+		p.exit(res.ExitCode, err, stdout, stderr)
+		return p, nil
+	}
+
+	// Output is copied by os/exec, so WaitDelay also applies to children keeping stdout or stderr open.
+	e.Stdout = stdout
+	e.Stderr = io.MultiWriter(stderr, stderrTail)
+	start := time.Now()
+	if err := e.Start(); err != nil {
+		e.done()
+		err = e.error(err, 0, nil, false)
+		e.record(err, 0)
+		return nil, err
+	}
+	p.pid = e.Process.Pid
+	p.process = e.Process
+	p.waitDelay = e.WaitDelay
+	if p.waitDelay == 0 {
+		p.waitDelay = stopTimeout
+	}
+
+	go func() {
+		err := e.Wait()
+		e.done()
+		err = e.error(err, time.Since(start), stderrTail, false)
+		e.record(err, time.Since(start))
+		p.exit(e.ProcessState.ExitCode(), err, stdout, stderr)
+	}()
+	return p, nil
+}
+
+func newProcess(e *execution) *Process {
+	return &Process{
+		cmd:      e.cmd,
+		args:     e.args,
+		group:    e.group,
+		stdout:   make(chan string, lineBufferSize),
+		stderr:   make(chan string, lineBufferSize),
+		done:     make(chan struct{}),
+		exitCode: -1,
+	}
+}
+
+// Returns writers splitting stdout and stderr of the process into lines and a buffer capturing the end of stderr.
+func (r *Runner) outputWriters(ctx context.Context, p *Process) (stdout, stderr *lineWriter, stderrTail *tailBuffer) {
+	stdoutW := outOrStdoutIfNil(ctx, r.stdout)
+	stderrW := outOrStderrIfNil(ctx, r.stderr)
+	if logPrefixFromContext(ctx) {
+		stdoutW = taskWriter{stdoutW, p.cmd, "OUT"}
+		stderrW = taskWriter{stderrW, p.cmd, "ERR"}
+	}
+	return &lineWriter{p: p, out: stdoutW, lines: p.stdout},
+		&lineWriter{p: p, out: stderrW, lines: p.stderr},
+		&tailBuffer{}
+}
+
+// Records the exit of the process, once no more output is written.
+func (p *Process) exit(exitCode int, err error, stdout, stderr *lineWriter) {
+	stdout.close()
+	stderr.close()
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.err = err
+	p.exitCode = exitCode
+	for _, w := range p.watchers {
+		close(w.match)
+	}
+	p.watchers = nil
+	close(p.done)
+}
+
+// Splits output into lines, that are written to out and handed to the process.
+type lineWriter struct {
+	p     *Process
+	out   io.Writer
+	lines chan string
+	// incomplete last line.
+	buf []byte
+}
+
+func (w *lineWriter) Write(b []byte) (int, error) {
+	w.buf = append(w.buf, b...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.p.addLine(string(bytes.TrimSuffix(w.buf[:i], []byte{'\r'})), w.out, w.lines)
+		w.buf = w.buf[i+1:]
+	}
+	if len(w.buf) > maxLineSize {
+		w.p.addLine(string(w.buf), w.out, w.lines)
+		w.buf = nil
+	}
+	return len(b), nil
+}
+
+// Flushes an incomplete last line and closes the lines channel.
+func (w *lineWriter) close() {
+	if len(w.buf) > 0 {
+		w.p.addLine(string(w.buf), w.out, w.lines)
+		w.buf = nil
+	}
+	close(w.lines)
+}
+
+func (p *Process) addLine(line string, out io.Writer, lines chan<- string) {
+	_, _ = out.Write([]byte(line + "\n"))
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.history = append(p.history, line)
+	if len(p.history) > lineHistorySize {
+		p.history = p.history[len(p.history)-lineHistorySize:]
+	}
+	watchers := p.watchers[:0]
+	for _, w := range p.watchers {
+		if w.re.MatchString(line) {
+			w.match <- line
+			close(w.match)
+			continue
+		}
+		watchers = append(watchers, w)
+	}
+	p.watchers = watchers
+	select {
+	case lines <- line:
+	default:
+		p.dropped++
+	}
+}
+
+// PID returns the process id, 0 if the process was faked by a Recorder in dry run mode.
+func (p *Process) PID() int {
+	return p.pid
+}
+
+// Stdout returns a channel receiving stdout line by line.
+// Lines are dropped when the channel is not read fast enough.
+// The channel is closed when the stream ends.
+func (p *Process) Stdout() <-chan string {
+	return p.stdout
+}
+
+// Stderr returns a channel receiving stderr line by line.
+// Lines are dropped when the channel is not read fast enough.
+// The channel is closed when the stream ends.
+func (p *Process) Stderr() <-chan string {
+	return p.stderr
+}
+
+// Dropped returns the number of lines not delivered, because a channel was full.
+func (p *Process) Dropped() int {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return p.dropped
+}
+
+// Lines returns the most recent lines of stdout and stderr in order of arrival.
+func (p *Process) Lines() []string {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return append([]string(nil), p.history...)
+}
+
+// Done returns a channel that is closed when the process exited.
+func (p *Process) Done() <-chan struct{} {
+	return p.done
+}
+
+// Wait waits for the process to exit.
+// Returns an error like Runner.Run, if the process did not exit successfully.
+func (p *Process) Wait() error {
+	<-p.done
+	return p.err
+}
+
+// ExitCode returns the exit code of the exited process
+// or -1 if the process is still running or was terminated by a signal.
+func (p *Process) ExitCode() int {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return p.exitCode
+}
+
+// String returns the command line of the process.
+func (p *Process) String() string {
+	return strings.TrimSpace(p.cmd + " " + strings.Join(p.args, " "))
+}
+
+// Stop terminates the process, or its process group if started via Runner.Background or WithProcessGroup,
+// and kills it if it did not exit within the runners wait delay, 5 seconds by default.
+// Returns once the process exited.
+func (p *Process) Stop() error {
+	select {
+	case <-p.done:
+		return nil
+	default:
+	}
+	if p.process == nil {
+		// Faked by a Recorder in dry run mode.
+		<-p.done
+		return nil
+	}
+	if err := terminate(p.process, p.group); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("terminating %q (pid %d): %w", p.cmd, p.pid, err)
+	}
+	select {
+	case <-p.done:
+		return nil
+	case <-time.After(p.waitDelay):
+	}
+	if err := kill(p.process, p.group); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("killing %q (pid %d): %w", p.cmd, p.pid, err)
+	}
+	<-p.done
+	return nil
+}
+
+// Signal sends a signal to the process.
+func (p *Process) Signal(sig os.Signal) error {
+	if p.process == nil {
+		return fmt.Errorf("signaling %q: %w", p.cmd, os.ErrProcessDone)
+	}
+	if err := p.process.Signal(sig); err != nil {
+		return fmt.Errorf("signaling %q (pid %d): %w", p.cmd, p.pid, err)
+	}
+	return nil
+}
+
+// WaitForLine blocks until stdout or stderr contains a line matching re and returns it.
+// Lines printed before WaitForLine was called are considered as well.
+// Fails if the process exits or ctx is cancelled before a matching line was printed.
+func (p *Process) WaitForLine(ctx context.Context, re *regexp.Regexp) (string, error) {
+	p.mux.Lock()
+	for _, line := range p.history {
+		if re.MatchString(line) {
+			p.mux.Unlock()
+			return line, nil
+		}
+	}
+	w := &lineWatcher{re: re, match: make(chan string, 1)}
+	select {
+	case <-p.done:
+		close(w.match)
+	default:
+		p.watchers = append(p.watchers, w)
+	}
+	p.mux.Unlock()
+
+	select {
+	case line, ok := <-w.match:
+		if !ok {
+			return "", fmt.Errorf(`"%s %s" exited before printing a line matching %q`,
+				p.cmd, strings.Join(p.args, " "), re)
+		}
+		return line, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
@@ -0,0 +1,180 @@
+package sh
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Recorder records commands executed by Runners and optionally fakes their results instead of executing them.
+// Attach it via the WithRecorder option or ContextWithRecorder.
+type Recorder struct {
+	// Trace receives one JSON document per executed command, if set.
+	Trace io.Writer
+	// Do not execute commands, but return the first matching fake result.
+	DryRun bool
+	// Results of commands in dry run mode, the first result matching the command line is used.
+	// Commands without matching result succeed without output.
+	Results []FakeResult
+
+	mux      sync.Mutex
+	commands []RecordedCommand
+}
+
+// FakeResult is returned for commands matching Pattern in dry run mode.
+type FakeResult struct {
+	// Matched against the command line, e.g. "go test ./...", or the script of Runner.Bash.
+	Pattern  *regexp.Regexp
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// RecordedCommand describes an executed command.
+type RecordedCommand struct {
+	Cmd  string   `json:"cmd"`
+	Args []string `json:"args,omitempty"`
+	// Environment variables set by the Runner, that differ from the process environment.
+	Env     map[string]string `json:"env,omitempty"`
+	WorkDir string            `json:"workDir,omitempty"`
+	// Input of the command, e.g. the script of Runner.Bash.
+	Stdin string `json:"stdin,omitempty"`
+	// Whether the command was started in the background via Runner.Start or Runner.Background.
+	Background bool          `json:"background,omitempty"`
+	DryRun     bool          `json:"dryRun,omitempty"`
+	Duration   time.Duration `json:"durationNanoseconds"`
+	// Exit code of the command, -1 if it could not be started or was terminated by a signal.
+	ExitCode int    `json:"exitCode"`
+	Error    string `json:"error,omitempty"`
+}
+
+// String returns the command line.
+func (c RecordedCommand) String() string {
+	return strings.TrimSpace(c.Cmd + " " + strings.Join(c.Args, " "))
+}
+
+// Commands returns all commands recorded so far, in order of completion.
+func (rec *Recorder) Commands() []RecordedCommand {
+	rec.mux.Lock()
+	defer rec.mux.Unlock()
+	return append([]RecordedCommand(nil), rec.commands...)
+}
+
+func (rec *Recorder) record(c RecordedCommand) {
+	rec.mux.Lock()
+	defer rec.mux.Unlock()
+	rec.commands = append(rec.commands, c)
+	if rec.Trace == nil {
+		return
+	}
+	// Tracing must not fail the command, encoding these types can not fail.
+	b, _ := json.Marshal(c)
+	_, _ = rec.Trace.Write(append(b, '\n'))
+}
+
+func (rec *Recorder) result(cmd string, args []string, stdin string) FakeResult {
+	match := stdin
+	if len(match) == 0 {
+		match = strings.TrimSpace(cmd + " " + strings.Join(args, " "))
+	}
+	for _, res := range rec.Results {
+		if res.Pattern == nil || res.Pattern.MatchString(match) {
+			return res
+		}
+	}
+	return FakeResult{}
+}
+
+type recorderContextKey struct{}
+
+// ContextWithRecorder returns a copy of ctx that makes Runners without explicitly configured recorder use rec.
+func ContextWithRecorder(ctx context.Context, rec *Recorder) context.Context {
+	return context.WithValue(ctx, recorderContextKey{}, rec)
+}
+
+// RecorderFromContext returns the Recorder set via ContextWithRecorder, if any.
+func RecorderFromContext(ctx context.Context) (*Recorder, bool) {
+	rec, ok := ctx.Value(recorderContextKey{}).(*Recorder)
+	return rec, ok
+}
+
+func recorderOrFromContext(ctx context.Context, rec *Recorder) *Recorder {
+	if rec != nil {
+		return rec
+	}
+	if ctxRec, ok := RecorderFromContext(ctx); ok {
+		return ctxRec
+	}
+	return nil
+}
+
+// Records the finished command, if a recorder is configured.
+func (e *execution) record(err error, took time.Duration) {
+	if e.recorder == nil {
+		return
+	}
+	c := RecordedCommand{
+		Cmd:        e.cmd,
+		Args:       e.args,
+		WorkDir:    e.workDir,
+		Stdin:      e.stdin,
+		Background: e.async,
+		DryRun:     e.recorder.DryRun,
+		Duration:   took,
+	}
+	for k, v := range e.env {
+		if current, ok := os.LookupEnv(k); ok && current == v {
+			continue
+		}
+		if c.Env == nil {
+			c.Env = map[string]string{}
+		}
+		c.Env[k] = v
+	}
+	if err != nil {
+		c.ExitCode = -1
+		c.Error = err.Error()
+		var exitErr *ExitError
+		if errors.As(err, &exitErr) {
+			c.ExitCode = exitErr.ExitCode
+		}
+	}
+	e.recorder.record(c)
+}
+
+// Writes the fake result of the command in dry run mode.
+func (e *execution) fake(stdout, stderr io.Writer, stderrTail *tailBuffer, showStderr bool) error {
+	res := e.recorder.result(e.cmd, e.args, e.stdin)
+	if len(res.Stdout) > 0 {
+		if _, err := io.WriteString(stdout, res.Stdout); err != nil {
+			return err
+		}
+	}
+	if len(res.Stderr) > 0 {
+		if _, err := io.WriteString(stderr, res.Stderr); err != nil {
+			return err
+		}
+	}
+	return e.fakeError(res, stderrTail, showStderr)
+}
+
+func (e *execution) fakeError(res FakeResult, stderrTail *tailBuffer, showStderr bool) error {
+	if res.ExitCode == 0 {
+		return nil
+	}
+	return &ExitError{
+		Cmd:        e.cmd,
+		Args:       e.args,
+		ExitCode:   res.ExitCode,
+		Stderr:     stderrTail.String(),
+		Err:        fmt.Errorf("exit status %d", res.ExitCode),
+		showStderr: showStderr,
+	}
+}
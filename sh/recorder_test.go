@@ -0,0 +1,89 @@
+package sh_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pkg.package-operator.run/cardboard/sh"
+)
+
+func TestRecorder_trace(t *testing.T) {
+	t.Setenv("CARDBOARD_NO_LOG_PREFIX", "1")
+	var trace, stdout bytes.Buffer
+	rec := &sh.Recorder{Trace: &trace}
+	r := sh.New(
+		sh.WithRecorder{rec}, sh.WithStdout{&stdout}, sh.WithStderr{io.Discard},
+		sh.WithEnvironment{"RECORDER_TEST": "1"}, sh.WithWorkDir(t.TempDir()),
+	)
+
+	require.NoError(t, r.Run(t.Context(), "echo", "hello"))
+	require.Error(t, r.Bash(t.Context(), "exit 3"))
+	assert.Equal(t, "hello\n", stdout.String())
+
+	cmds := rec.Commands()
+	require.Len(t, cmds, 2)
+	assert.Equal(t, "echo hello", cmds[0].String())
+	assert.Equal(t, map[string]string{"RECORDER_TEST": "1"}, cmds[0].Env)
+	assert.NotEmpty(t, cmds[0].WorkDir)
+	assert.Equal(t, 0, cmds[0].ExitCode)
+	assert.Positive(t, cmds[0].Duration)
+	assert.False(t, cmds[0].DryRun)
+	assert.Equal(t, "exit 3", cmds[1].Stdin)
+	assert.Equal(t, 3, cmds[1].ExitCode)
+	assert.Equal(t, `running "bash " failed with exit code 3`, cmds[1].Error)
+
+	lines := strings.Split(strings.TrimSpace(trace.String()), "\n")
+	require.Len(t, lines, 2)
+	var traced sh.RecordedCommand
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &traced))
+	assert.Equal(t, cmds[0], traced)
+}
+
+func TestRecorder_dryRun(t *testing.T) {
+	t.Setenv("CARDBOARD_NO_LOG_PREFIX", "1")
+	sentinel := filepath.Join(t.TempDir(), "ran")
+	rec := &sh.Recorder{
+		DryRun: true,
+		Results: []sh.FakeResult{
+			{Pattern: regexp.MustCompile(`^git rev-parse`), Stdout: "abc123\n"},
+			{Pattern: regexp.MustCompile(`^go test`), Stderr: "FAIL\n", ExitCode: 1},
+			{Pattern: regexp.MustCompile(`touch`), Stdout: "script\n"},
+		},
+	}
+	var out bytes.Buffer
+	r := sh.New(sh.WithCombinedOutput{&out})
+	ctx := sh.ContextWithRecorder(t.Context(), rec)
+
+	head, err := r.Output(ctx, "git", "rev-parse", "HEAD")
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", head)
+
+	err = r.Run(ctx, "go", "test", "./...")
+	require.EqualError(t, err, `running "go test ./..." failed with exit code 1`)
+
+	require.NoError(t, r.Bash(ctx, "touch "+sentinel))
+	assert.NoFileExists(t, sentinel, "scripts are not executed")
+	require.NoError(t, r.Run(ctx, "does-not-exist"), "unmatched commands succeed")
+	assert.Equal(t, "FAIL\nscript\n", out.String())
+
+	p, err := r.Background(ctx, sh.TCPProbe{Addr: "localhost:1"}, "registry", "serve")
+	require.NoError(t, err, "probes are skipped")
+	require.NoError(t, p.Wait())
+	require.NoError(t, p.Stop())
+
+	cmds := rec.Commands()
+	require.Len(t, cmds, 5)
+	for _, c := range cmds {
+		assert.True(t, c.DryRun)
+	}
+	assert.Equal(t, 1, cmds[1].ExitCode)
+	assert.True(t, cmds[4].Background)
+}
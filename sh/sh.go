@@ -19,6 +19,11 @@ type Runner struct {
 	env            map[string]string
 	stdout, stderr io.Writer
 	workDir        string
+	readyTimeout   time.Duration
+	processGroup   bool
+	waitDelay      time.Duration
+	timeout        time.Duration
+	recorder       *Recorder
 }
 
 func New(opts ...RunnerOption) *Runner {
@@ -34,6 +39,12 @@ func (r *Runner) New(opts ...RunnerOption) *Runner {
 		stdout:  r.stdout,
 		stderr:  r.stderr,
 		workDir: r.workDir,
+
+		readyTimeout: r.readyTimeout,
+		processGroup: r.processGroup,
+		waitDelay:    r.waitDelay,
+		timeout:      r.timeout,
+		recorder:     r.recorder,
 	}
 	nr.apply(opts...)
 	return nr
@@ -72,24 +83,23 @@ func (t taskWriter) Write(p []byte) (n int, err error) {
 }
 
 func (r *Runner) Run(ctx context.Context, cmd string, args ...string) error {
-	stdout := outOrStdoutIfNil(r.stdout)
-	stderr := outOrStderrIfNil(r.stderr)
+	stdout := outOrStdoutIfNil(ctx, r.stdout)
+	stderr := outOrStderrIfNil(ctx, r.stderr)
 
-	if os.Getenv("CARDBOARD_NO_LOG_PREFIX") == "" {
+	if logPrefixFromContext(ctx) {
 		stdout = taskWriter{stdout, cmd, "OUT"}
 		stderr = taskWriter{stderr, cmd, "ERR"}
 	}
 
-	return r.run(ctx, stdout, stderr, nil, cmd, args...)
+	return r.run(ctx, stdout, stderr, "", cmd, args...)
 }
 
 func (r *Runner) Bash(ctx context.Context, script ...string) error {
-	scriptBuf := bytes.NewBufferString(strings.Join(script, "\n"))
 	if err := r.run(
 		ctx,
-		outOrStdoutIfNil(r.stdout),
-		outOrStderrIfNil(r.stderr),
-		scriptBuf,
+		outOrStdoutIfNil(ctx, r.stdout),
+		outOrStderrIfNil(ctx, r.stderr),
+		strings.Join(script, "\n"),
 		"bash",
 	); err != nil {
 		return fmt.Errorf("failed to run bash script: %w\nscript: %s", err, strings.Join(script, "\n"))
@@ -99,7 +109,7 @@ func (r *Runner) Bash(ctx context.Context, script ...string) error {
 
 func (r *Runner) Output(ctx context.Context, cmd string, args ...string) (string, error) {
 	var out bytes.Buffer
-	err := r.run(ctx, &out, outOrStderrIfNil(r.stderr), nil, cmd, args...)
+	err := r.run(ctx, &out, outOrStderrIfNil(ctx, r.stderr), "", cmd, args...)
 	return strings.TrimRight(out.String(), "\n"), err
 }
 
@@ -117,41 +127,151 @@ func (r *Runner) Copy(dst, src string) error {
 	return os.WriteFile(dst, data, info.Mode().Perm()) //nolint:gosec // Traversal allowed here.
 }
 
-func (r *Runner) run(ctx context.Context, stdout, stderr io.Writer, stdin io.Reader, cmd string, args ...string) error {
-	c := exec.CommandContext(ctx, cmd, args...)
+// Runs the command, script is passed via stdin if not empty.
+func (r *Runner) run(ctx context.Context, stdout, stderr io.Writer, script string, cmd string, args ...string) error {
+	e, err := r.command(ctx, false, script, cmd, args...)
+	if err != nil {
+		return err
+	}
+	defer e.done()
+
+	// Capture stderr for errors and only show it in the message, if it is not written anywhere else.
+	var stderrTail tailBuffer
+	showStderr := stderr == nil
+	switch {
+	case stderr == nil:
+		stderr = &stderrTail
+	case !sameWriter(stdout, stderr):
+		// Combined output has to stay a single writer to keep the order of lines.
+		stderr = io.MultiWriter(stderr, &stderrTail)
+	}
+
+	start := time.Now()
+	if e.dryRun() {
+		err = e.fake(stdout, stderr, &stderrTail, showStderr)
+		e.record(err, time.Since(start))
+		return err
+	}
+
+	if len(script) > 0 {
+		e.Stdin = strings.NewReader(script)
+	}
+	e.Stdout = stdout
+	e.Stderr = stderr
+
+	err = e.error(e.Run(), time.Since(start), &stderrTail, showStderr)
+	e.record(err, time.Since(start))
+	return err
+}
+
+// Reports whether a and b are the same writer, like os/exec does to detect combined output.
+func sameWriter(a, b io.Writer) (same bool) {
+	defer func() {
+		// Comparing non-comparable types panics.
+		if recover() != nil {
+			same = false
+		}
+	}()
+	return a == b
+}
+
+// errTimeout is the cause of contexts cancelled by the runners timeout.
+var errTimeout = errors.New("command timed out")
+
+// execution is a command prepared by a Runner.
+type execution struct {
+	*exec.Cmd
+	cmd     string
+	args    []string
+	env     map[string]string
+	workDir string
+	// recorded input of the command.
+	stdin string
+	// started via Runner.Start or Runner.Background.
+	async    bool
+	group    bool
+	timeout  time.Duration
+	recorder *Recorder
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Prepares the command with the runners environment, working directory, timeout and cancellation.
+// Background processes always run in their own process group.
+// done has to be called once the command exited.
+func (r *Runner) command(
+	ctx context.Context, background bool, script string, cmd string, args ...string,
+) (*execution, error) {
+	if hook, ok := CommandHookFromContext(ctx); ok {
+		if err := hook(ctx, cmd, args, script); err != nil {
+			return nil, fmt.Errorf(`preparing "%s %s": %w`, cmd, strings.Join(args, " "), err)
+		}
+	}
+
+	e := &execution{
+		cmd:      cmd,
+		args:     args,
+		stdin:    script,
+		env:      r.env,
+		workDir:  r.workDir,
+		group:    background || r.processGroup,
+		timeout:  r.timeout,
+		recorder: recorderOrFromContext(ctx, r.recorder),
+	}
+	if r.timeout > 0 {
+		e.ctx, e.cancel = context.WithTimeoutCause(ctx, r.timeout, errTimeout)
+	} else {
+		e.ctx, e.cancel = context.WithCancel(ctx)
+	}
+
+	c := exec.CommandContext(e.ctx, cmd, args...)
 	c.Env = os.Environ()
 	for k, v := range r.env {
 		c.Env = append(c.Env, k+"="+v)
 	}
+	c.Dir = r.workDir
 
-	var stderrBuf bytes.Buffer
-	if stderr == nil {
-		stderr = &stderrBuf
+	waitDelay := r.waitDelay
+	if waitDelay == 0 && e.group {
+		waitDelay = stopTimeout
 	}
-
-	c.Stdin = stdin
-	c.Stdout = stdout
-	c.Stderr = stderr
-	c.Dir = r.workDir
+	if e.group {
+		setProcessGroup(c)
+	}
+	switch {
+	case e.group && waitDelay > 0:
+		// os/exec only kills the direct process after WaitDelay, so remaining children are killed here.
+		c.Cancel = func() error {
+			time.AfterFunc(waitDelay, func() { _ = kill(c.Process, true) })
+			return terminate(c.Process, true)
+		}
+		c.WaitDelay = waitDelay
+	case waitDelay > 0:
+		c.Cancel = func() error { return terminate(c.Process, false) }
+		c.WaitDelay = waitDelay
+	}
+	e.Cmd = c
 
 	if r.logger != nil {
 		r.logger.InfoContext(ctx, "exec", slog.String("cmd", cmd), slog.String("args", strings.Join(args, ", ")))
 	}
+	return e, nil
+}
 
-	err := c.Run()
-	if err == nil {
-		return nil
-	}
-	if cmdRan(err) {
-		code := exitStatus(err)
-		if stderrBuf.Len() > 0 {
-			return fmt.Errorf(`running "%s %s" failed with exit code %d: %s`,
-				cmd, strings.Join(args, " "), code, strings.TrimRight(stderrBuf.String(), "\n"),
-			)
-		}
-		return fmt.Errorf(`running "%s %s" failed with exit code %d`, cmd, strings.Join(args, " "), code)
-	}
-	return fmt.Errorf(`failed to run "%s %s": %w`, cmd, strings.Join(args, " "), err)
+// Releases the timeout context.
+func (e *execution) done() {
+	e.cancel()
+}
+
+// Reports whether the command is not executed, but faked by the recorder.
+func (e *execution) dryRun() bool {
+	return e.recorder != nil && e.recorder.DryRun
+}
+
+// Reports whether the command was cancelled because it exceeded the runners timeout.
+func (e *execution) timedOut() bool {
+	return e.timeout > 0 && errors.Is(context.Cause(e.ctx), errTimeout)
 }
 
 // cmdRan examines the error to determine if it was generated as a result of a
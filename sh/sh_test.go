@@ -1,7 +1,15 @@
 package sh_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"regexp"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/neilotoole/slogt"
 	"github.com/stretchr/testify/assert"
@@ -37,3 +45,147 @@ func TestRunner_Output(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "hello world", out)
 }
+
+func TestRunner_Bash_contextOutput(t *testing.T) {
+	t.Parallel()
+	log := slogt.New(t)
+	var out bytes.Buffer
+	ctx := sh.ContextWithOutput(t.Context(), &out)
+	err := sh.New(sh.WithLogger{log}).Bash(ctx, "echo hello", "echo world >&2")
+	require.NoError(t, err)
+	assert.Equal(t, "hello\nworld\n", out.String())
+}
+
+func TestRunner_Run_contextWithoutLogPrefix(t *testing.T) {
+	t.Parallel()
+	var prefixed, plain bytes.Buffer
+	require.NoError(t, sh.New(sh.WithStdout{&prefixed}).Run(t.Context(), "echo", "hello"))
+	assert.Contains(t, prefixed.String(), " [OUT echo] hello\n")
+
+	ctx := sh.ContextWithoutLogPrefix(t.Context())
+	require.NoError(t, sh.New(sh.WithStdout{&plain}).Run(ctx, "echo", "hello"))
+	assert.Equal(t, "hello\n", plain.String())
+}
+
+func TestRunner_Run_noLogPrefixEnv(t *testing.T) {
+	t.Setenv("CARDBOARD_NO_LOG_PREFIX", "1")
+	var out bytes.Buffer
+	require.NoError(t, sh.New(sh.WithStdout{&out}).Run(t.Context(), "echo", "hello"))
+	assert.Equal(t, "hello\n", out.String(), "without context setting")
+}
+
+func TestRunner_Run_commandHook(t *testing.T) {
+	t.Parallel()
+	var hooked []string
+	ctx := sh.ContextWithCommandHook(t.Context(), func(_ context.Context, cmd string, _ []string, _ string) error {
+		hooked = append(hooked, cmd)
+		if cmd == "false" {
+			return errors.New("not available")
+		}
+		return nil
+	})
+	require.NoError(t, sh.New().Run(ctx, "true"))
+	require.EqualError(t, sh.New().Run(ctx, "false", "arg"), `preparing "false arg": not available`)
+	assert.Equal(t, []string{"true", "false"}, hooked)
+}
+
+func TestRunner_Start(t *testing.T) {
+	t.Parallel()
+	var stdout, stderr bytes.Buffer
+	r := sh.New(sh.WithStdout{&stdout}, sh.WithStderr{&stderr})
+	p, err := r.Start(t.Context(), "bash", "-c", "echo starting; echo warming up >&2; echo ready; exit 3")
+	require.NoError(t, err)
+	assert.Positive(t, p.PID())
+
+	line, err := p.WaitForLine(t.Context(), regexp.MustCompile("^warm"))
+	require.NoError(t, err)
+	assert.Equal(t, "warming up", line)
+
+	require.EqualError(t, p.Wait(),
+		`running "bash -c echo starting; echo warming up >&2; echo ready; exit 3" failed with exit code 3`)
+	assert.Equal(t, 3, p.ExitCode())
+	assert.Equal(t, []string{"starting", "ready"}, collect(p.Stdout()))
+	assert.Equal(t, []string{"warming up"}, collect(p.Stderr()))
+	assert.Contains(t, stdout.String(), "[OUT bash] ready\n")
+	assert.Contains(t, stderr.String(), "[ERR bash] warming up\n")
+
+	line, err = p.WaitForLine(t.Context(), regexp.MustCompile("ready"))
+	require.NoError(t, err, "considers earlier lines")
+	assert.Equal(t, "ready", line)
+}
+
+func TestProcess_Signal(t *testing.T) {
+	t.Parallel()
+	p, err := sh.New(sh.WithCombinedOutput{io.Discard}).Start(t.Context(), "bash", "-c", "echo ready; sleep 60")
+	require.NoError(t, err)
+	_, err = p.WaitForLine(t.Context(), regexp.MustCompile("ready"))
+	require.NoError(t, err)
+	assert.Equal(t, -1, p.ExitCode(), "still running")
+
+	require.NoError(t, p.Signal(syscall.SIGTERM))
+	require.EqualError(t, p.Wait(), `failed to run "bash -c echo ready; sleep 60": signal: terminated`)
+	assert.Equal(t, -1, p.ExitCode())
+
+	_, err = p.WaitForLine(t.Context(), regexp.MustCompile("never"))
+	require.EqualError(t, err, `"bash -c echo ready; sleep 60" exited before printing a line matching "never"`)
+}
+
+func collect(lines <-chan string) []string {
+	var all []string
+	for line := range lines {
+		all = append(all, line)
+	}
+	return all
+}
+
+func TestRunner_Run_exitError(t *testing.T) {
+	t.Parallel()
+	var stderr bytes.Buffer
+	err := sh.New(sh.WithStderr{&stderr}, sh.WithStdout{io.Discard}).
+		Run(t.Context(), "bash", "-c", "echo oops >&2; exit 2")
+	require.EqualError(t, err, `running "bash -c echo oops >&2; exit 2" failed with exit code 2`)
+
+	var exitErr *sh.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, "bash", exitErr.Cmd)
+	assert.Equal(t, []string{"-c", "echo oops >&2; exit 2"}, exitErr.Args)
+	assert.Equal(t, 2, exitErr.ExitCode)
+	assert.Nil(t, exitErr.Signal)
+	assert.Equal(t, "oops\n", exitErr.Stderr)
+	assert.Positive(t, exitErr.Duration)
+}
+
+func TestRunner_Output_exitError(t *testing.T) {
+	t.Parallel()
+	_, err := sh.New().Output(t.Context(), "bash", "-c", "kill -TERM $$")
+
+	var exitErr *sh.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, -1, exitErr.ExitCode)
+	assert.Equal(t, syscall.SIGTERM, exitErr.Signal)
+	require.EqualError(t, err, `failed to run "bash -c kill -TERM $$": signal: terminated`)
+}
+
+func TestRunner_Run_startError(t *testing.T) {
+	t.Parallel()
+	err := sh.New().Run(t.Context(), "xxxxxxxxxxx", "arg")
+
+	var startErr *sh.StartError
+	require.ErrorAs(t, err, &startErr)
+	assert.Equal(t, "xxxxxxxxxxx", startErr.Cmd)
+	require.ErrorIs(t, err, exec.ErrNotFound)
+	require.NotErrorAs(t, err, new(*sh.ExitError))
+}
+
+func TestRunner_WithTimeout(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+	err := sh.New(sh.WithTimeout(100*time.Millisecond)).Run(t.Context(), "sleep", "10")
+	assert.Less(t, time.Since(start), 5*time.Second)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.EqualError(t, err, `running "sleep 10" timed out after 100ms: signal: killed`)
+
+	var exitErr *sh.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 100*time.Millisecond, exitErr.Timeout)
+}
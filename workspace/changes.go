@@ -0,0 +1,203 @@
+package workspace
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"pkg.package-operator.run/cardboard/sh"
+)
+
+// Go package of a workspace module.
+type Package struct {
+	ImportPath string
+	// Absolute path of the package directory.
+	Dir string
+	// Path of the module providing the package.
+	Module string
+}
+
+// ChangedFiles returns the files changed compared to the base git ref, relative to the workspace root.
+// Includes committed changes since the merge base with base, uncommitted changes and untracked files.
+// Only the local git repository is consulted, base is not fetched.
+func (w *Workspace) ChangedFiles(ctx context.Context, base string) ([]string, error) {
+	git := sh.New(sh.WithWorkDir(w.Root))
+	var files []string
+	for _, args := range [][]string{
+		{"diff", "--name-only", "--relative", "--no-renames", base + "...HEAD"},
+		{"diff", "--name-only", "--relative", "--no-renames", "HEAD"},
+		{"ls-files", "--others", "--exclude-standard"},
+	} {
+		out, err := git.Output(ctx, "git", args...)
+		if err != nil {
+			return nil, fmt.Errorf("listing changed files against %s: %w", base, err)
+		}
+		for _, f := range strings.Split(out, "\n") {
+			if f = strings.TrimSpace(f); len(f) > 0 {
+				files = append(files, filepath.FromSlash(f))
+			}
+		}
+	}
+	slices.Sort(files)
+	return slices.Compact(files), nil
+}
+
+// AffectedModules returns modules containing any of the given files
+// and all workspace modules depending on them.
+func (w *Workspace) AffectedModules(files []string) []Module {
+	affected := map[string]bool{}
+	for _, f := range files {
+		if filepath.Clean(f) == "go.work" || filepath.Clean(f) == "go.work.sum" {
+			return w.Modules
+		}
+		m, ok := w.ModuleOf(f)
+		if !ok || affected[m.Path] {
+			continue
+		}
+		affected[m.Path] = true
+		for _, d := range w.Dependents(m.Path) {
+			affected[d.Path] = true
+		}
+	}
+
+	var modules []Module
+	for _, m := range w.Modules {
+		if affected[m.Path] {
+			modules = append(modules, m)
+		}
+	}
+	return modules
+}
+
+// AffectedPackages returns the packages containing any of the given files
+// and all packages importing them, directly, transitively or from tests.
+// Changes to go.mod or go.sum affect all packages of the module, changes to go.work all packages.
+// Files outside of packages, like testdata, affect the closest parent package of the same module.
+func (w *Workspace) AffectedPackages(ctx context.Context, files []string) ([]Package, error) {
+	entries, err := w.listPackages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pkgs []Package
+	byDir := map[string]Package{}
+	for _, e := range entries {
+		if len(e.ForTest) == 0 && !strings.HasSuffix(e.ImportPath, ".test") {
+			p := Package{ImportPath: e.ImportPath, Dir: e.Dir}
+			if e.Module != nil {
+				p.Module = e.Module.Path
+			}
+			pkgs = append(pkgs, p)
+			byDir[p.Dir] = p
+		}
+	}
+
+	changed := map[string]bool{}
+	for _, f := range files {
+		for _, p := range w.changedPackages(f, pkgs, byDir) {
+			changed[p.ImportPath] = true
+		}
+	}
+
+	affected := map[string]bool{}
+	for _, e := range entries {
+		pkg := e.ImportPath
+		if len(e.ForTest) > 0 {
+			pkg = e.ForTest
+		}
+		if changed[pkg] || slices.ContainsFunc(e.Deps, func(dep string) bool { return changed[variantOf(dep)] }) {
+			affected[pkg] = true
+		}
+	}
+
+	var result []Package
+	for _, p := range pkgs {
+		if affected[p.ImportPath] {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+// Returns the packages directly changed by the given file.
+func (w *Workspace) changedPackages(file string, pkgs []Package, byDir map[string]Package) []Package {
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(w.Root, file)
+	}
+	m, ok := w.ModuleOf(file)
+	if !ok {
+		return nil
+	}
+
+	switch rel, _ := filepath.Rel(w.Root, file); filepath.Base(rel) {
+	case "go.work", "go.work.sum":
+		if filepath.Dir(rel) == "." {
+			return pkgs
+		}
+	case "go.mod", "go.sum":
+		if filepath.ToSlash(filepath.Dir(rel)) == m.Dir {
+			var modulePkgs []Package
+			for _, p := range pkgs {
+				if p.Module == m.Path {
+					modulePkgs = append(modulePkgs, p)
+				}
+			}
+			return modulePkgs
+		}
+	}
+
+	moduleDir := filepath.Join(w.Root, filepath.FromSlash(m.Dir))
+	for dir := filepath.Dir(file); ; dir = filepath.Dir(dir) {
+		if p, ok := byDir[dir]; ok && p.Module == m.Path {
+			return []Package{p}
+		}
+		if dir == moduleDir || dir == filepath.Dir(dir) {
+			return nil
+		}
+	}
+}
+
+type listEntry struct {
+	ImportPath string
+	Dir        string
+	ForTest    string
+	Deps       []string
+	Module     *struct{ Path string }
+}
+
+// Lists all packages of the workspace including test variants.
+func (w *Workspace) listPackages(ctx context.Context) ([]listEntry, error) {
+	args := []string{"list", "-e", "-test", "-json=ImportPath,Dir,ForTest,Deps,Module"}
+	for _, m := range w.Modules {
+		args = append(args, m.Packages())
+	}
+	out, err := sh.New(sh.WithWorkDir(w.Root)).Output(ctx, "go", args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing packages: %w", err)
+	}
+
+	var entries []listEntry
+	dec := json.NewDecoder(strings.NewReader(out))
+	for {
+		var e listEntry
+		err := dec.Decode(&e)
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decoding package list: %w", err)
+		}
+		entries = append(entries, e)
+	}
+}
+
+// Strips the test variant suffix, e.g. "pkg [pkg.test]" -> "pkg".
+func variantOf(importPath string) string {
+	p, _, _ := strings.Cut(importPath, " ")
+	return p
+}
@@ -0,0 +1,134 @@
+package workspace
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+}
+
+// Workspace of three modules: app imports lib, lib imports root.
+func testGoWorkspace(t *testing.T) string {
+	t.Helper()
+	root := testWorkspace(t)
+	writeFile(t, filepath.Join(root, "lib", "go.mod"), "module example.com/root/lib\n\ngo 1.26.0\n\n"+
+		"require example.com/root v0.0.0\n\nreplace example.com/root => ../\n")
+	writeFile(t, filepath.Join(root, "modules", "app", "go.mod"), "module example.com/root/modules/app\n\ngo 1.26.0\n\n"+
+		"require example.com/root/lib v0.0.0\n\nreplace example.com/root/lib => ../../lib\n")
+
+	writeFile(t, filepath.Join(root, "util", "util.go"), "package util\n\nfunc Util() {}\n")
+	writeFile(t, filepath.Join(root, "other", "other.go"), "package other\n\nfunc Other() {}\n")
+	writeFile(t, filepath.Join(root, "other", "other_test.go"),
+		"package other\n\nimport (\n\t\"testing\"\n\n\t\"example.com/root/util\"\n)\n\n"+
+			"func TestOther(t *testing.T) { util.Util() }\n")
+	writeFile(t, filepath.Join(root, "lib", "lib.go"),
+		"package lib\n\nimport \"example.com/root/util\"\n\nfunc Lib() { util.Util() }\n")
+	writeFile(t, filepath.Join(root, "lib", "testdata", "data.txt"), "data\n")
+	writeFile(t, filepath.Join(root, "modules", "app", "main.go"),
+		"package main\n\nimport \"example.com/root/lib\"\n\nfunc main() { lib.Lib() }\n")
+
+	git(t, root, "init", "-q", "-b", "main")
+	git(t, root, "add", "-A")
+	git(t, root, "commit", "-q", "-m", "initial")
+	return root
+}
+
+func TestWorkspace_ChangedFiles(t *testing.T) {
+	t.Parallel()
+	root := testGoWorkspace(t)
+	git(t, root, "checkout", "-q", "-b", "feature")
+	writeFile(t, filepath.Join(root, "util", "util.go"), "package util\n\nfunc Util() { _ = 1 }\n")
+	git(t, root, "commit", "-q", "-am", "change")
+	writeFile(t, filepath.Join(root, "lib", "lib.go"), "package lib\n\nfunc Lib() {}\n")
+	writeFile(t, filepath.Join(root, "new.txt"), "new\n")
+
+	w, err := Load(root)
+	require.NoError(t, err)
+	files, err := w.ChangedFiles(t.Context(), "main")
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join("lib", "lib.go"), "new.txt", filepath.Join("util", "util.go")}, files)
+
+	_, err = w.ChangedFiles(t.Context(), "does-not-exist")
+	require.Error(t, err)
+}
+
+func TestWorkspace_AffectedModules(t *testing.T) {
+	t.Parallel()
+	w, err := Load(testWorkspace(t))
+	require.NoError(t, err)
+
+	dirs := func(modules []Module) []string {
+		var ds []string
+		for _, m := range modules {
+			ds = append(ds, m.Dir)
+		}
+		return ds
+	}
+	assert.Equal(t, []string{"lib", "modules/app"}, dirs(w.AffectedModules([]string{"lib/lib.go"})))
+	assert.Equal(t, []string{"modules/app"}, dirs(w.AffectedModules([]string{"modules/app/go.sum"})))
+	assert.Equal(t, []string{".", "lib", "modules/app"}, dirs(w.AffectedModules([]string{"go.work"})))
+	assert.Empty(t, w.AffectedModules([]string{"../elsewhere.go"}))
+}
+
+func TestWorkspace_AffectedPackages(t *testing.T) {
+	t.Parallel()
+	w, err := Load(testGoWorkspace(t))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		files    []string
+		expected []string
+	}{
+		{
+			name:  "reverse dependencies and test imports",
+			files: []string{"util/util.go"},
+			expected: []string{
+				"example.com/root/other", "example.com/root/util", "example.com/root/lib", "example.com/root/modules/app",
+			},
+		},
+		{
+			name:     "testdata",
+			files:    []string{"lib/testdata/data.txt"},
+			expected: []string{"example.com/root/lib", "example.com/root/modules/app"},
+		},
+		{
+			name:  "go.mod",
+			files: []string{"go.mod"},
+			expected: []string{
+				"example.com/root/other", "example.com/root/util", "example.com/root/lib", "example.com/root/modules/app",
+			},
+		},
+		{
+			name:     "leaf",
+			files:    []string{"modules/app/main.go"},
+			expected: []string{"example.com/root/modules/app"},
+		},
+		{
+			name:  "no package",
+			files: []string{"README.md"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			pkgs, err := w.AffectedPackages(t.Context(), test.files)
+			require.NoError(t, err)
+			var paths []string
+			for _, p := range pkgs {
+				paths = append(paths, p.ImportPath)
+			}
+			assert.ElementsMatch(t, test.expected, paths)
+		})
+	}
+}
@@ -0,0 +1,188 @@
+// Package workspace parses go.work and go.mod files to expose the Go modules of a project.
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+
+	"pkg.package-operator.run/cardboard/cache"
+)
+
+// Go module that is part of a workspace.
+type Module struct {
+	// Module path as declared in go.mod, e.g. "pkg.package-operator.run/cardboard".
+	Path string
+	// Directory of the module relative to the workspace root, e.g. "." or "modules/kind".
+	Dir string
+	// Go version declared in go.mod.
+	GoVersion string
+	// Paths of all modules required in go.mod.
+	Requires []string
+}
+
+// Returns the package pattern matching all packages of the module, e.g. "./modules/kind/...".
+func (m Module) Packages() string {
+	if m.Dir == "." {
+		return "./..."
+	}
+	return "./" + m.Dir + "/..."
+}
+
+// Go workspace of one or more modules.
+type Workspace struct {
+	// Absolute path of the workspace root directory.
+	Root string
+	// Modules in order of the go.work use directives.
+	Modules []Module
+}
+
+// Load parses the go.work file in root, or the go.mod file if there is no go.work file.
+func Load(root string) (*Workspace, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	w := &Workspace{Root: root}
+
+	dirs, err := useDirs(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range dirs {
+		m, err := loadModule(root, dir)
+		if err != nil {
+			return nil, err
+		}
+		w.Modules = append(w.Modules, m)
+	}
+	return w, nil
+}
+
+// Current loads the workspace of the project root.
+func Current() (*Workspace, error) {
+	root, err := cache.ProjectRoot()
+	if err != nil {
+		return nil, err
+	}
+	return Load(root)
+}
+
+// Module returns the workspace module with the given module path.
+func (w *Workspace) Module(path string) (Module, bool) {
+	for _, m := range w.Modules {
+		if m.Path == path {
+			return m, true
+		}
+	}
+	return Module{}, false
+}
+
+// ModuleOf returns the module containing the given file.
+// Relative paths are resolved against the workspace root.
+func (w *Workspace) ModuleOf(file string) (Module, bool) {
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(w.Root, file)
+	}
+	rel, err := filepath.Rel(w.Root, file)
+	if err != nil || !filepath.IsLocal(rel) {
+		return Module{}, false
+	}
+	rel = filepath.ToSlash(rel)
+
+	// Nested modules take precedence, so pick the longest matching directory.
+	var (
+		found Module
+		ok    bool
+	)
+	for _, m := range w.Modules {
+		if m.Dir != "." && rel != m.Dir && !strings.HasPrefix(rel, m.Dir+"/") {
+			continue
+		}
+		if !ok || len(m.Dir) > len(found.Dir) || found.Dir == "." {
+			found, ok = m, true
+		}
+	}
+	return found, ok
+}
+
+// Dependents returns all workspace modules requiring the module with the given path, directly or transitively.
+func (w *Workspace) Dependents(path string) []Module {
+	seen := map[string]bool{path: true}
+	queue := []string{path}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, m := range w.Modules {
+			if !seen[m.Path] && slices.Contains(m.Requires, current) {
+				seen[m.Path] = true
+				queue = append(queue, m.Path)
+			}
+		}
+	}
+
+	var dependents []Module
+	for _, m := range w.Modules {
+		if m.Path != path && seen[m.Path] {
+			dependents = append(dependents, m)
+		}
+	}
+	return dependents
+}
+
+// Returns the module directories of the workspace relative to root.
+func useDirs(root string) ([]string, error) {
+	workFile := filepath.Join(root, "go.work")
+	data, err := os.ReadFile(workFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return []string{"."}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading go.work: %w", err)
+	}
+	wf, err := modfile.ParseWork(workFile, data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("parsing go.work: %w", err)
+	}
+
+	dirs := make([]string, len(wf.Use))
+	for i, use := range wf.Use {
+		dir := use.Path
+		if filepath.IsAbs(dir) {
+			if dir, err = filepath.Rel(root, dir); err != nil {
+				return nil, err
+			}
+		}
+		dirs[i] = filepath.ToSlash(filepath.Clean(dir))
+	}
+	return dirs, nil
+}
+
+func loadModule(root, dir string) (Module, error) {
+	modFile := filepath.Join(root, filepath.FromSlash(dir), "go.mod")
+	data, err := os.ReadFile(modFile)
+	if err != nil {
+		return Module{}, fmt.Errorf("reading go.mod of %s: %w", dir, err)
+	}
+	mf, err := modfile.ParseLax(modFile, data, nil)
+	if err != nil {
+		return Module{}, fmt.Errorf("parsing go.mod of %s: %w", dir, err)
+	}
+	if mf.Module == nil {
+		return Module{}, fmt.Errorf("go.mod of %s: missing module directive", dir)
+	}
+
+	m := Module{Path: mf.Module.Mod.Path, Dir: dir}
+	if mf.Go != nil {
+		m.GoVersion = mf.Go.Version
+	}
+	for _, req := range mf.Require {
+		m.Requires = append(m.Requires, req.Mod.Path)
+	}
+	return m, nil
+}
@@ -0,0 +1,112 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, file, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(file), 0o755))
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o600))
+}
+
+func testWorkspace(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "go.work"), "go 1.26.0\n\nuse (\n\t.\n\t./lib\n\t./modules/app\n)\n")
+	writeFile(t, filepath.Join(root, "go.mod"), "module example.com/root\n\ngo 1.26.0\n")
+	writeFile(t, filepath.Join(root, "lib", "go.mod"),
+		"module example.com/root/lib\n\ngo 1.25.0\n\nrequire example.com/root v0.0.0\n")
+	writeFile(t, filepath.Join(root, "modules", "app", "go.mod"),
+		"module example.com/root/modules/app\n\ngo 1.26.0\n\nrequire example.com/root/lib v0.0.0\n")
+	return root
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+	root := testWorkspace(t)
+
+	w, err := Load(root)
+	require.NoError(t, err)
+	assert.Equal(t, root, w.Root)
+	assert.Equal(t, []Module{
+		{Path: "example.com/root", Dir: ".", GoVersion: "1.26.0"},
+		{Path: "example.com/root/lib", Dir: "lib", GoVersion: "1.25.0", Requires: []string{"example.com/root"}},
+		{
+			Path: "example.com/root/modules/app", Dir: "modules/app", GoVersion: "1.26.0",
+			Requires: []string{"example.com/root/lib"},
+		},
+	}, w.Modules)
+
+	assert.Equal(t, "./...", w.Modules[0].Packages())
+	assert.Equal(t, "./modules/app/...", w.Modules[2].Packages())
+}
+
+func TestLoad_singleModule(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "go.mod"), "module example.com/single\n\ngo 1.26.0\n")
+
+	w, err := Load(root)
+	require.NoError(t, err)
+	assert.Equal(t, []Module{{Path: "example.com/single", Dir: ".", GoVersion: "1.26.0"}}, w.Modules)
+}
+
+func TestLoad_missingModule(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "go.work"), "go 1.26.0\n\nuse ./missing\n")
+
+	_, err := Load(root)
+	require.ErrorContains(t, err, "reading go.mod of missing")
+}
+
+func TestWorkspace_ModuleOf(t *testing.T) {
+	t.Parallel()
+	root := testWorkspace(t)
+	w, err := Load(root)
+	require.NoError(t, err)
+
+	tests := []struct {
+		file string
+		dir  string
+		ok   bool
+	}{
+		{file: "main.go", dir: ".", ok: true},
+		{file: "lib/lib.go", dir: "lib", ok: true},
+		{file: "library/x.go", dir: ".", ok: true},
+		{file: "modules/app/cmd/main.go", dir: "modules/app", ok: true},
+		{file: filepath.Join(root, "lib", "go.mod"), dir: "lib", ok: true},
+		{file: "../outside.go"},
+	}
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			t.Parallel()
+			m, ok := w.ModuleOf(test.file)
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.dir, m.Dir)
+		})
+	}
+}
+
+func TestWorkspace_Dependents(t *testing.T) {
+	t.Parallel()
+	w, err := Load(testWorkspace(t))
+	require.NoError(t, err)
+
+	m, ok := w.Module("example.com/root/lib")
+	require.True(t, ok)
+	assert.Equal(t, "lib", m.Dir)
+
+	var dirs []string
+	for _, d := range w.Dependents("example.com/root") {
+		dirs = append(dirs, d.Dir)
+	}
+	assert.Equal(t, []string{"lib", "modules/app"}, dirs)
+	assert.Empty(t, w.Dependents("example.com/root/modules/app"))
+}